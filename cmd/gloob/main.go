@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"gloob-interpreter/internal/builtins"
+	"gloob-interpreter/internal/colors"
+	"gloob-interpreter/internal/errors"
+	"gloob-interpreter/internal/imports"
+	"gloob-interpreter/internal/interpreter"
+	"gloob-interpreter/internal/lexer"
+	"gloob-interpreter/internal/parser"
+	"gloob-interpreter/internal/scope"
+	"gloob-interpreter/internal/values"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Exit codes returned by the gloob command.
+const (
+	exitOK    = 0 // Everything went fine
+	exitError = 1 // The script failed to parse, import or run
+	exitUsage = 2 // The command line itself was wrong
+)
+
+const usage = `🫧 Gloob - a small, playful, interpreted language
+
+Usage:
+  gloob                     Start the interactive REPL
+  gloob <file>              Run a Gloob file
+  gloob run <file>          Run a Gloob file
+  gloob repl                Start the interactive REPL
+  gloob check <file>        Parse a file and resolve its imports without running it
+  gloob eval -e '<code>'    Evaluate a snippet of Gloob code
+  gloob help                Show this help
+
+run, check and eval accept -path <dirs> to search for modules in more directories,
+separated like in the GLOOB_PATH environment variable, which is searched after them.
+`
+
+func main() {
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// runCommand dispatches the command line to the matching subcommand
+// and returns the process exit code.
+func runCommand(args []string) int {
+	if len(args) == 0 {
+		return repl()
+	}
+
+	switch args[0] {
+	case "run":
+		return runSubcommand(args[1:])
+	case "repl":
+		return repl()
+	case "check":
+		return checkSubcommand(args[1:])
+	case "eval":
+		return evalSubcommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return exitOK
+	}
+
+	// `gloob file.gloob` is a shortcut for `gloob run file.gloob`
+	if !strings.HasPrefix(args[0], "-") {
+		return runSubcommand(args)
+	}
+
+	return usageError("unknown command '%s'", args[0])
+}
+
+// parseModuleFlags parses the flags of the subcommands running a file, which configure
+// where modules are searched, and returns the remaining arguments.
+func parseModuleFlags(name string, args []string) ([]string, bool) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := flags.String("path", "", "Directories to search for modules")
+	if err := flags.Parse(args); err != nil {
+		return nil, false
+	}
+	imports.AddSearchPath(filepath.SplitList(*path)...)
+	return flags.Args(), true
+}
+
+// usageError prints a command line error followed by the usage text.
+func usageError(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "%s %s\n\n", colors.Red("Error:"), fmt.Sprintf(format, args...))
+	fmt.Fprint(os.Stderr, usage)
+	return exitUsage
+}
+
+// runSubcommand implements `gloob run [-path <dirs>] <file>`.
+func runSubcommand(args []string) int {
+	args, ok := parseModuleFlags("run", args)
+	if !ok {
+		return exitUsage
+	}
+	if len(args) != 1 {
+		return usageError("run expects exactly one file")
+	}
+
+	program, sourceCode, ok := loadProgram(args[0])
+	if !ok {
+		return exitError
+	}
+
+	s := newGlobalScope(sourceCode)
+	if _, err := interpreter.Evaluate(program, s); err != nil {
+		errors.Report(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// checkSubcommand implements `gloob check [-path <dirs>] <file>`.
+// The file and every module it imports are parsed, but nothing is evaluated.
+func checkSubcommand(args []string) int {
+	args, ok := parseModuleFlags("check", args)
+	if !ok {
+		return exitUsage
+	}
+	if len(args) != 1 {
+		return usageError("check expects exactly one file")
+	}
+
+	program, sourceCode, ok := loadProgram(args[0])
+	if !ok {
+		return exitError
+	}
+	if err := imports.Check(program, sourceCode, args[0]); err != nil {
+		errors.Report(os.Stderr, err)
+		return exitError
+	}
+
+	fmt.Printf("%s %s\n", colors.Green("✔"), args[0])
+	return exitOK
+}
+
+// evalSubcommand implements `gloob eval [-path <dirs>] -e '<code>'`.
+func evalSubcommand(args []string) int {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	code := flags.String("e", "", "Gloob code to evaluate")
+	path := flags.String("path", "", "Directories to search for modules")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	imports.AddSearchPath(filepath.SplitList(*path)...)
+	if *code == "" {
+		return usageError("eval expects code passed with -e")
+	}
+
+	p := parser.NewParser(nil)
+	program, err := p.ProduceASTWithFilename(*code, "<eval>")
+	if err != nil {
+		errors.Report(os.Stderr, err)
+		return exitError
+	}
+
+	s := newGlobalScope(*code)
+	result, err := interpreter.Evaluate(program, s)
+	if err != nil {
+		errors.Report(os.Stderr, err)
+		return exitError
+	}
+	printResult(result)
+	return exitOK
+}
+
+// repl starts the interactive Read-Eval-Print Loop.
+// Every line shares the same global scope so variables and functions persist,
+// and errors are reported without leaving the loop.
+func repl() int {
+	fmt.Println("🫧 Gloob REPL - type 'exit' to quit")
+
+	s := newGlobalScope("")
+	reader := bufio.NewScanner(os.Stdin)
+
+	// Lines are buffered until their brackets balance, so functions and blocks can span several lines
+	var buffer []string
+	for {
+		if buffer == nil {
+			fmt.Print(colors.Blue("> "))
+		} else {
+			fmt.Print(colors.Blue(". "))
+		}
+		if !reader.Scan() {
+			fmt.Println()
+			break
+		}
+
+		line := strings.TrimSpace(reader.Text())
+		if buffer == nil {
+			if line == "" {
+				continue
+			}
+			if line == "exit" || line == "quit" {
+				break
+			}
+		}
+
+		buffer = append(buffer, reader.Text())
+		source := strings.Join(buffer, "\n")
+		if isIncomplete(source) {
+			continue
+		}
+		buffer = nil
+
+		p := parser.NewParser(nil)
+		program, err := p.ProduceASTWithFilename(source, "<repl>")
+		if err != nil {
+			errors.Report(os.Stderr, err)
+			continue
+		}
+
+		s.SetSourceCode(source)
+		result, err := interpreter.Evaluate(program, s)
+		if err != nil {
+			errors.Report(os.Stderr, err)
+			continue
+		}
+		printResult(result)
+	}
+
+	fmt.Println("Bye!")
+	return exitOK
+}
+
+// isIncomplete reports whether the source still has open parentheses, brackets or braces,
+// or ends inside a triple-quoted or template string, which can span several lines.
+// Brackets inside strings and comments don't count.
+func isIncomplete(source string) bool {
+	lines := strings.Split(source, "\n")
+	open := 0
+	for _, token := range lexer.NewLexer(source, "<repl>").Tokenize() {
+		switch token.Type {
+		case lexer.TokenTypeOpenParentheses, lexer.TokenTypeOpenSquareBrackets, lexer.TokenTypeOpenCurlyBrackets:
+			open++
+		case lexer.TokenTypeCloseParentheses, lexer.TokenTypeCloseSquareBrackets, lexer.TokenTypeCloseCurlyBrackets:
+			open--
+		case lexer.TokenTypeUnknown:
+			// Unterminated strings are unknown tokens starting at their opening quotes
+			if token.Line < 1 || token.Line > len(lines) {
+				break
+			}
+			line := []rune(lines[token.Line-1])
+			if token.ColumnStart < 1 || token.ColumnStart > len(line) {
+				break
+			}
+			opening := string(line[token.ColumnStart-1:])
+			if strings.HasPrefix(opening, "`") || strings.HasPrefix(opening, `"""`) || strings.HasPrefix(opening, "'''") {
+				return true
+			}
+		}
+	}
+	return open > 0
+}
+
+// loadProgram reads and parses a Gloob file. Its imports are evaluated when the program runs.
+// Problems are reported to the user and signaled through the returned bool.
+func loadProgram(filename string) (*parser.Program, string, bool) {
+	sourceCode, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s could not read %s: %v\n", colors.Red("Error:"), filename, err)
+		return nil, "", false
+	}
+
+	p := parser.NewParser(nil)
+	program, err := p.ProduceASTWithFilename(string(sourceCode), filename)
+	if err != nil {
+		errors.Report(os.Stderr, err)
+		return nil, "", false
+	}
+
+	return program, string(sourceCode), true
+}
+
+// newGlobalScope creates the top level scope with all the built-ins declared.
+func newGlobalScope(sourceCode string) *scope.Scope {
+	s := builtins.NewGlobalScope()
+	s.SetSourceCode(sourceCode)
+	return s
+}
+
+// printResult echoes the value of an evaluated snippet, skipping values
+// that carry no information for the user (null, declarations, functions).
+func printResult(result values.RuntimeValue) {
+	if result == nil {
+		return
+	}
+	switch result.NodeType() {
+	case parser.NodeTypeNull, parser.NodeTypeVariableDeclaration,
+		parser.NodeTypeFunctionDeclaration, parser.NodeTypeNativeFunction:
+		return
+	}
+	fmt.Println(result)
+}
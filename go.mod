@@ -2,8 +2,9 @@ module gloob-interpreter
 
 go 1.24.1
 
+require github.com/fatih/color v1.18.0
+
 require (
-	github.com/fatih/color v1.18.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	golang.org/x/sys v0.25.0 // indirect
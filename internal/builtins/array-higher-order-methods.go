@@ -0,0 +1,481 @@
+package builtins
+
+import (
+	"fmt"
+	"gloob-interpreter/internal/errors"
+	"gloob-interpreter/internal/parser"
+	"gloob-interpreter/internal/values"
+	"sort"
+)
+
+// Higher-order array methods take a function and call it for the elements of the array.
+// Callbacks receive the element and its 1-based index, and may declare fewer parameters:
+//
+//	[1, 2, 3].map(x => x * 2)
+//	[1, 2, 3].map((x, i) => x * i)
+//	[1, 2, 3].map(string)
+
+// ArrayMapMethod returns a new array with the results of calling a function on every element
+func ArrayMapMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("map", args)
+			if err != nil {
+				return nil, err
+			}
+
+			elements := make([]values.RuntimeValue, 0, len(array.Elements))
+			for i, element := range array.Elements {
+				result, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, result)
+			}
+			return newArray(elements), nil
+		},
+	}
+}
+
+// ArrayFilterMethod returns a new array with the elements for which the function returns a truthy value
+func ArrayFilterMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("filter", args)
+			if err != nil {
+				return nil, err
+			}
+
+			elements := []values.RuntimeValue{}
+			for i, element := range array.Elements {
+				result, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope)
+				if err != nil {
+					return nil, err
+				}
+				if values.IsTruthy(result) {
+					elements = append(elements, element)
+				}
+			}
+			return newArray(elements), nil
+		},
+	}
+}
+
+// ArrayReduceMethod combines the elements into a single value.
+// The function receives the accumulator, the element and its index.
+// Without an initial value the first element is used as the accumulator.
+func ArrayReduceMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return nil, argCountError("reduce", "1 or 2 arguments (function, initial value)", len(args))
+			}
+			if !isCallable(args[0]) {
+				return nil, argTypeError("reduce", "a function argument")
+			}
+
+			elements := array.Elements
+			start := 0
+			var accumulator values.RuntimeValue
+			if len(args) == 2 {
+				accumulator = args[1]
+			} else {
+				if len(elements) == 0 {
+					return nil, errors.RuntimeError(nil, "", errors.ErrReduceEmptyArray)
+				}
+				accumulator = elements[0]
+				start = 1
+			}
+
+			for i := start; i < len(elements); i++ {
+				result, err := callCallback(args[0], []values.RuntimeValue{accumulator, elements[i], index(i)}, 1, scope)
+				if err != nil {
+					return nil, err
+				}
+				accumulator = result
+			}
+			return accumulator, nil
+		},
+	}
+}
+
+// ArrayFindMethod returns the first element for which the function returns a truthy value, or null
+func ArrayFindMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("find", args)
+			if err != nil {
+				return nil, err
+			}
+
+			i, err := findIndex(array, callback, scope)
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 {
+				return &values.NullValue{Type: parser.NodeTypeNull}, nil
+			}
+			return array.Elements[i], nil
+		},
+	}
+}
+
+// ArrayFindIndexMethod returns the 1-based index of the first element for which
+// the function returns a truthy value. Returns 0 if not found, like indexOf.
+func ArrayFindIndexMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("findIndex", args)
+			if err != nil {
+				return nil, err
+			}
+
+			i, err := findIndex(array, callback, scope)
+			if err != nil {
+				return nil, err
+			}
+			return index(i), nil
+		},
+	}
+}
+
+// ArraySomeMethod checks if the function returns a truthy value for at least one element
+func ArraySomeMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("some", args)
+			if err != nil {
+				return nil, err
+			}
+
+			i, err := findIndex(array, callback, scope)
+			if err != nil {
+				return nil, err
+			}
+			return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: i >= 0}, nil
+		},
+	}
+}
+
+// ArrayEveryMethod checks if the function returns a truthy value for all the elements
+func ArrayEveryMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("every", args)
+			if err != nil {
+				return nil, err
+			}
+
+			for i, element := range array.Elements {
+				result, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope)
+				if err != nil {
+					return nil, err
+				}
+				if !values.IsTruthy(result) {
+					return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: false}, nil
+				}
+			}
+			return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: true}, nil
+		},
+	}
+}
+
+// ArrayForEachMethod calls the function for every element
+func ArrayForEachMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("forEach", args)
+			if err != nil {
+				return nil, err
+			}
+
+			for i, element := range array.Elements {
+				if _, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope); err != nil {
+					return nil, err
+				}
+			}
+			return &values.NullValue{Type: parser.NodeTypeNull}, nil
+		},
+	}
+}
+
+// ArrayFlatMapMethod maps every element and flattens the resulting arrays one level into a new array
+func ArrayFlatMapMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("flatMap", args)
+			if err != nil {
+				return nil, err
+			}
+
+			elements := []values.RuntimeValue{}
+			for i, element := range array.Elements {
+				result, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope)
+				if err != nil {
+					return nil, err
+				}
+				if nested, ok := result.(*values.ArrayValue); ok {
+					elements = append(elements, nested.Elements...)
+				} else {
+					elements = append(elements, result)
+				}
+			}
+			return newArray(elements), nil
+		},
+	}
+}
+
+// ArraySortMethod sorts the array in-place.
+// Without arguments numbers and strings are sorted in ascending order.
+// A comparator function (a, b) returns a negative number when a goes first,
+// a positive number when b goes first, and 0 when they are equal.
+func ArraySortMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) > 1 {
+				return nil, argCountError("sort", "0 or 1 argument (comparator)", len(args))
+			}
+
+			compare := compareValues
+			if len(args) == 1 {
+				if !isCallable(args[0]) {
+					return nil, argTypeError("sort", "a comparator function")
+				}
+				compare = func(a, b values.RuntimeValue) (int, error) {
+					result, err := callCallback(args[0], []values.RuntimeValue{a, b}, 0, scope)
+					if err != nil {
+						return 0, err
+					}
+					number, ok := result.(*values.NumericValue)
+					if !ok {
+						return 0, argTypeError("sort", "a comparator returning a number")
+					}
+					return sign(number.Value), nil
+				}
+			}
+
+			sorted, err := sortElements(array.Elements, array.Elements, compare)
+			if err != nil {
+				return nil, err
+			}
+			array.Elements = sorted
+			return array, nil
+		},
+	}
+}
+
+// ArraySortByMethod sorts the array in-place by the key the function returns for every element
+func ArraySortByMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("sortBy", args)
+			if err != nil {
+				return nil, err
+			}
+
+			keys := make([]values.RuntimeValue, len(array.Elements))
+			for i, element := range array.Elements {
+				key, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope)
+				if err != nil {
+					return nil, err
+				}
+				keys[i] = key
+			}
+
+			sorted, err := sortElements(array.Elements, keys, compareValues)
+			if err != nil {
+				return nil, err
+			}
+			array.Elements = sorted
+			return array, nil
+		},
+	}
+}
+
+// ArrayGroupByMethod groups the elements into an object of arrays, keyed by what the function returns
+func ArrayGroupByMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			callback, err := callbackArgument("groupBy", args)
+			if err != nil {
+				return nil, err
+			}
+
+			groups := values.NewObjectValue()
+			for i, element := range array.Elements {
+				key, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope)
+				if err != nil {
+					return nil, err
+				}
+				name := fmt.Sprint(key)
+				group, ok := groups.Properties[name].(*values.ArrayValue)
+				if !ok {
+					group = newArray([]values.RuntimeValue{})
+					groups.Set(name, group)
+				}
+				group.Elements = append(group.Elements, element)
+			}
+			return groups, nil
+		},
+	}
+}
+
+// ArrayUniqueMethod returns a new array without repeated elements, keeping the first occurrence
+func ArrayUniqueMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			elements := []values.RuntimeValue{}
+			for _, element := range array.Elements {
+				seen := false
+				for _, kept := range elements {
+					if values.Equal(kept, element) {
+						seen = true
+						break
+					}
+				}
+				if !seen {
+					elements = append(elements, element)
+				}
+			}
+			return newArray(elements), nil
+		},
+	}
+}
+
+// ArrayZipMethod pairs the elements of two arrays: [1, 2].zip(["a", "b"]) is [[1, "a"], [2, "b"]].
+// The result is as long as the shorter array.
+func ArrayZipMethod(array *values.ArrayValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 1 {
+				return nil, argCountError("zip", "1 argument (array)", len(args))
+			}
+			other, ok := args[0].(*values.ArrayValue)
+			if !ok {
+				return nil, argTypeError("zip", "an array argument")
+			}
+
+			length := len(array.Elements)
+			if len(other.Elements) < length {
+				length = len(other.Elements)
+			}
+
+			pairs := make([]values.RuntimeValue, length)
+			for i := 0; i < length; i++ {
+				pairs[i] = newArray([]values.RuntimeValue{array.Elements[i], other.Elements[i]})
+			}
+			return newArray(pairs), nil
+		},
+	}
+}
+
+// callbackArgument checks that a method was called with a single function argument
+func callbackArgument(name string, args []values.RuntimeValue) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError(name, "1 argument (function)", len(args))
+	}
+	if !isCallable(args[0]) {
+		return nil, argTypeError(name, "a function argument")
+	}
+	return args[0], nil
+}
+
+// findIndex returns the 0-based index of the first element for which
+// the callback returns a truthy value, or -1 if there is none
+func findIndex(array *values.ArrayValue, callback values.RuntimeValue, scope interface{}) (int, error) {
+	for i, element := range array.Elements {
+		result, err := callCallback(callback, []values.RuntimeValue{element, index(i)}, 1, scope)
+		if err != nil {
+			return -1, err
+		}
+		if values.IsTruthy(result) {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// sortElements returns a sorted copy of elements, ordering them by their keys.
+// The sort is stable and the first comparison error stops it.
+func sortElements(elements []values.RuntimeValue, keys []values.RuntimeValue, compare func(a, b values.RuntimeValue) (int, error)) ([]values.RuntimeValue, error) {
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+
+	var sortErr error
+	sort.SliceStable(order, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		result, err := compare(keys[order[i]], keys[order[j]])
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		return result < 0
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	sorted := make([]values.RuntimeValue, len(elements))
+	for i, position := range order {
+		sorted[i] = elements[position]
+	}
+	return sorted, nil
+}
+
+// compareValues orders two numbers or two strings
+func compareValues(a, b values.RuntimeValue) (int, error) {
+	switch left := a.(type) {
+	case *values.NumericValue:
+		if right, ok := b.(*values.NumericValue); ok {
+			return values.CompareNumbers(left, right), nil
+		}
+	case *values.StringValue:
+		if right, ok := b.(*values.StringValue); ok {
+			switch {
+			case left.Value < right.Value:
+				return -1, nil
+			case left.Value > right.Value:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, errors.RuntimeError(nil, "", errors.ErrCannotCompareTypes, a.NodeType(), b.NodeType(), "<")
+}
+
+func sign(value float64) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+	return 0
+}
+
+// index converts a 0-based position into the 1-based index seen by Gloob code
+func index(i int) *values.NumericValue {
+	return values.NewInt(int64(i + 1))
+}
+
+func newArray(elements []values.RuntimeValue) *values.ArrayValue {
+	return &values.ArrayValue{Type: parser.NodeTypeArray, Elements: elements}
+}
@@ -2,23 +2,21 @@ package builtins
 
 import (
 	"fmt"
+	"gloob-interpreter/internal/errors"
 	"gloob-interpreter/internal/parser"
 	"gloob-interpreter/internal/values"
-	"os"
 )
 
 // ArrayPushMethod adds an element to the end of an array
 func ArrayPushMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("push() expects 1 argument, got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("push", "1 argument", len(args))
 			}
 			array.Elements = append(array.Elements, args[0])
-			return array
+			return array, nil
 		},
 	}
 }
@@ -27,16 +25,14 @@ func ArrayPushMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayPopMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(array.Elements) == 0 {
-				fmt.Printf("Cannot pop from empty array\n")
-				os.Exit(1)
-				return nil
+				return nil, errors.RuntimeError(nil, "", errors.ErrPopFromEmptyArray)
 			}
 			lastIndex := len(array.Elements) - 1
 			lastElement := array.Elements[lastIndex]
 			array.Elements = array.Elements[:lastIndex]
-			return lastElement
+			return lastElement, nil
 		},
 	}
 }
@@ -45,11 +41,8 @@ func ArrayPopMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayLenMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
-			return &values.NumericValue{
-				Type:  parser.NodeTypeNumeric,
-				Value: float64(len(array.Elements)),
-			}
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			return values.NewInt(int64(len(array.Elements))), nil
 		},
 	}
 }
@@ -58,27 +51,22 @@ func ArrayLenMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayRemoveMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("remove() expects 1 argument (index), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("remove", "1 argument (index)", len(args))
 			}
-			if args[0].NodeType() != parser.NodeTypeNumeric {
-				fmt.Printf("remove() expects numeric index\n")
-				os.Exit(1)
-				return nil
+			position, ok := args[0].(*values.NumericValue)
+			if !ok || !position.IsInt {
+				return nil, argTypeError("remove", "an int index")
 			}
-			index := int(args[0].(*values.NumericValue).Value)
+			index := int(position.Int)
 			// Convert 1-based to 0-based
 			index = index - 1
 			if index < 0 || index >= len(array.Elements) {
-				fmt.Printf("Array index out of bounds: %d\n", index+1)
-				os.Exit(1)
-				return nil
+				return nil, errors.RuntimeError(nil, "", errors.ErrArrayIndexOutOfBounds, index+1, len(array.Elements))
 			}
 			array.Elements = append(array.Elements[:index], array.Elements[index+1:]...)
-			return array
+			return array, nil
 		},
 	}
 }
@@ -87,28 +75,23 @@ func ArrayRemoveMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayInsertMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 2 {
-				fmt.Printf("insert() expects 2 arguments (index, value), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("insert", "2 arguments (index, value)", len(args))
 			}
-			if args[0].NodeType() != parser.NodeTypeNumeric {
-				fmt.Printf("insert() expects numeric index\n")
-				os.Exit(1)
-				return nil
+			position, ok := args[0].(*values.NumericValue)
+			if !ok || !position.IsInt {
+				return nil, argTypeError("insert", "an int index")
 			}
-			index := int(args[0].(*values.NumericValue).Value)
+			index := int(position.Int)
 			// Convert 1-based to 0-based
 			index = index - 1
 			if index < 0 || index > len(array.Elements) {
-				fmt.Printf("Array index out of bounds: %d\n", index+1)
-				os.Exit(1)
-				return nil
+				return nil, errors.RuntimeError(nil, "", errors.ErrArrayIndexOutOfBounds, index+1, len(array.Elements))
 			}
 			// Insert element at index
 			array.Elements = append(array.Elements[:index], append([]values.RuntimeValue{args[1]}, array.Elements[index:]...)...)
-			return array
+			return array, nil
 		},
 	}
 }
@@ -118,30 +101,22 @@ func ArrayInsertMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayIndexOfMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("indexOf() expects 1 argument (element), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("indexOf", "1 argument (element)", len(args))
 			}
 
 			searchValue := args[0]
 			for i, element := range array.Elements {
 				// Simple equality check based on type and value
-				if elementsEqual(element, searchValue) {
+				if values.Equal(element, searchValue) {
 					// Return 1-based index
-					return &values.NumericValue{
-						Type:  parser.NodeTypeNumeric,
-						Value: float64(i + 1),
-					}
+					return values.NewInt(int64(i + 1)), nil
 				}
 			}
 
 			// Not found, return 0
-			return &values.NumericValue{
-				Type:  parser.NodeTypeNumeric,
-				Value: 0,
-			}
+			return values.NewInt(0), nil
 		},
 	}
 }
@@ -150,27 +125,25 @@ func ArrayIndexOfMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayContainsMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("contains() expects 1 argument (element), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("contains", "1 argument (element)", len(args))
 			}
 
 			searchValue := args[0]
 			for _, element := range array.Elements {
-				if elementsEqual(element, searchValue) {
+				if values.Equal(element, searchValue) {
 					return &values.BooleanValue{
 						Type:  parser.NodeTypeBoolean,
 						Value: true,
-					}
+					}, nil
 				}
 			}
 
 			return &values.BooleanValue{
 				Type:  parser.NodeTypeBoolean,
 				Value: false,
-			}
+			}, nil
 		},
 	}
 }
@@ -179,16 +152,12 @@ func ArrayContainsMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayJoinMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("join() expects 1 argument (separator), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("join", "1 argument (separator)", len(args))
 			}
 			if args[0].NodeType() != parser.NodeTypeString {
-				fmt.Printf("join() expects a string separator\n")
-				os.Exit(1)
-				return nil
+				return nil, argTypeError("join", "a string separator")
 			}
 
 			separator := args[0].(*values.StringValue).Value
@@ -197,7 +166,7 @@ func ArrayJoinMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 				return &values.StringValue{
 					Type:  parser.NodeTypeString,
 					Value: "",
-				}
+				}, nil
 			}
 
 			// Build the joined string
@@ -209,7 +178,7 @@ func ArrayJoinMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 			return &values.StringValue{
 				Type:  parser.NodeTypeString,
 				Value: result,
-			}
+			}, nil
 		},
 	}
 }
@@ -218,61 +187,66 @@ func ArrayJoinMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 func ArrayReverseMethod(array *values.ArrayValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			// Reverse the array in-place
 			for i, j := 0, len(array.Elements)-1; i < j; i, j = i+1, j-1 {
 				array.Elements[i], array.Elements[j] = array.Elements[j], array.Elements[i]
 			}
-			return array
+			return array, nil
 		},
 	}
 }
 
-// elementsEqual checks if two RuntimeValues are equal
-func elementsEqual(a, b values.RuntimeValue) bool {
-	if a.NodeType() != b.NodeType() {
-		return false
-	}
-
-	switch a.NodeType() {
-	case parser.NodeTypeNumeric:
-		return a.(*values.NumericValue).Value == b.(*values.NumericValue).Value
-	case parser.NodeTypeString:
-		return a.(*values.StringValue).Value == b.(*values.StringValue).Value
-	case parser.NodeTypeBoolean:
-		return a.(*values.BooleanValue).Value == b.(*values.BooleanValue).Value
-	case parser.NodeTypeNull:
-		return true
-	default:
-		// For complex types, use pointer comparison
-		return a == b
-	}
-}
-
 // GetArrayMethod returns the appropriate array method as a native function
-func GetArrayMethod(array *values.ArrayValue, methodName string) values.RuntimeValue {
+func GetArrayMethod(array *values.ArrayValue, methodName string) (values.RuntimeValue, error) {
 	switch methodName {
 	case "push":
-		return ArrayPushMethod(array)
+		return ArrayPushMethod(array), nil
 	case "pop":
-		return ArrayPopMethod(array)
+		return ArrayPopMethod(array), nil
 	case "len":
-		return ArrayLenMethod(array)
+		return ArrayLenMethod(array), nil
 	case "remove":
-		return ArrayRemoveMethod(array)
+		return ArrayRemoveMethod(array), nil
 	case "insert":
-		return ArrayInsertMethod(array)
+		return ArrayInsertMethod(array), nil
 	case "indexOf":
-		return ArrayIndexOfMethod(array)
+		return ArrayIndexOfMethod(array), nil
 	case "contains":
-		return ArrayContainsMethod(array)
+		return ArrayContainsMethod(array), nil
 	case "join":
-		return ArrayJoinMethod(array)
+		return ArrayJoinMethod(array), nil
 	case "reverse":
-		return ArrayReverseMethod(array)
+		return ArrayReverseMethod(array), nil
+	case "map":
+		return ArrayMapMethod(array), nil
+	case "filter":
+		return ArrayFilterMethod(array), nil
+	case "reduce":
+		return ArrayReduceMethod(array), nil
+	case "find":
+		return ArrayFindMethod(array), nil
+	case "findIndex":
+		return ArrayFindIndexMethod(array), nil
+	case "some":
+		return ArraySomeMethod(array), nil
+	case "every":
+		return ArrayEveryMethod(array), nil
+	case "forEach":
+		return ArrayForEachMethod(array), nil
+	case "flatMap":
+		return ArrayFlatMapMethod(array), nil
+	case "sort":
+		return ArraySortMethod(array), nil
+	case "sortBy":
+		return ArraySortByMethod(array), nil
+	case "groupBy":
+		return ArrayGroupByMethod(array), nil
+	case "unique":
+		return ArrayUniqueMethod(array), nil
+	case "zip":
+		return ArrayZipMethod(array), nil
 	default:
-		fmt.Printf("Unknown array method: %s\n", methodName)
-		os.Exit(1)
-		return nil
+		return nil, errors.RuntimeError(nil, "", errors.ErrUnknownArrayMethod, methodName)
 	}
 }
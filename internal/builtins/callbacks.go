@@ -0,0 +1,54 @@
+package builtins
+
+import (
+	"gloob-interpreter/internal/errors"
+	"gloob-interpreter/internal/parser"
+	"gloob-interpreter/internal/values"
+)
+
+// FunctionCaller calls a Gloob function (user-defined or native) with already evaluated arguments.
+// The scope is the one the interpreter handed to the native function making the call.
+type FunctionCaller func(function values.RuntimeValue, args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error)
+
+// functionCaller is provided by the interpreter, which imports this package and
+// therefore cannot be imported from here.
+var functionCaller FunctionCaller
+
+// SetFunctionCaller registers how native functions call back into Gloob functions.
+// The interpreter calls it once when it is initialized.
+func SetFunctionCaller(caller FunctionCaller) {
+	functionCaller = caller
+}
+
+// isCallable checks if a value can be called as a function
+func isCallable(value values.RuntimeValue) bool {
+	switch value.NodeType() {
+	case parser.NodeTypeFunctionDeclaration, parser.NodeTypeNativeFunction, parser.NodeTypeClass:
+		return true
+	default:
+		return false
+	}
+}
+
+// callCallback calls a function passed to a native method.
+// The last optional arguments of args, such as the index of an element, are extras:
+// Gloob functions are strict about their number of arguments, so a user function only
+// receives as many of them as it declares (all of them with a rest parameter) and other
+// functions don't receive them at all.
+// That way map(x => ...), map((x, i) => ...) and map(string) all work.
+func callCallback(function values.RuntimeValue, args []values.RuntimeValue, optional int, scope interface{}) (values.RuntimeValue, error) {
+	required := len(args) - optional
+	if fun, ok := function.(*values.FunctionValue); ok {
+		rest := len(fun.Parameters) > 0 && fun.Parameters[len(fun.Parameters)-1].Rest
+		if len(fun.Parameters) < len(args) && !rest {
+			args = args[:max(len(fun.Parameters), required)]
+		}
+	} else {
+		args = args[:required]
+	}
+
+	if functionCaller == nil {
+		return nil, errors.RuntimeError(nil, "", errors.ErrInvalidNativeFunction)
+	}
+	return functionCaller(function, args, scope)
+}
@@ -4,10 +4,11 @@ import (
 	"gloob-interpreter/internal/parser"
 	"gloob-interpreter/internal/scope"
 	"gloob-interpreter/internal/values"
+	"math"
 )
 
 // SetupConstants adds all built-in constants to the scope
 func SetupConstants(s *scope.Scope) {
 	s.Declare("null", &values.NullValue{Type: parser.NodeTypeNull}, true)
-	s.Declare("pi", &values.NumericValue{Type: parser.NodeTypeNumeric, Value: 3.141592653589793}, true)
+	s.Declare("pi", values.NewFloat(math.Pi), true)
 }
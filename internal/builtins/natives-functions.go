@@ -3,6 +3,7 @@ package builtins
 import (
 	"bufio"
 	"fmt"
+	"gloob-interpreter/internal/errors"
 	"gloob-interpreter/internal/parser"
 	"gloob-interpreter/internal/scope"
 	"gloob-interpreter/internal/values"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // SetupNativeFunctions adds all built-in native functions to the scope
@@ -35,10 +37,18 @@ func SetupNativeFunctions(s *scope.Scope) {
 
 	// Type conversion functions
 	DeclareNativeFunction(s, "number", NumberFunction)
+	DeclareNativeFunction(s, "int", IntFunction)
+	DeclareNativeFunction(s, "float", FloatFunction)
 	DeclareNativeFunction(s, "string", StringFunction)
 	DeclareNativeFunction(s, "bool", BoolFunction)
 	DeclareNativeFunction(s, "type", TypeFunction)
 
+	// Text encoding functions
+	DeclareNativeFunction(s, "bytes", BytesFunction)
+	DeclareNativeFunction(s, "runes", RunesFunction)
+	DeclareNativeFunction(s, "codepoint", CodepointFunction)
+	DeclareNativeFunction(s, "chr", ChrFunction)
+
 	// System functions
 	DeclareNativeFunction(s, "sleep", SleepFunction)
 	DeclareNativeFunction(s, "clear", ClearFunction)
@@ -48,15 +58,27 @@ func SetupNativeFunctions(s *scope.Scope) {
 	// Array methods (contains, indexOf, join, reverse) are available as: arr.contains(x), arr.join(", "), etc.
 }
 
-func DeclareNativeFunction(s *scope.Scope, name string, expression func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue) {
-	s.Declare(name, &values.NativeFunctionValue{
+// DeclareNativeFunction declares a Go function as a constant native function in the scope.
+func DeclareNativeFunction(s *scope.Scope, name string, expression func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error)) error {
+	_, err := s.Declare(name, &values.NativeFunctionValue{
 		Type:       parser.NodeTypeNativeFunction,
 		Expression: expression,
 	}, true)
+	return err
+}
+
+// argCountError reports a native function called with the wrong number of arguments.
+func argCountError(name string, expected string, got int) error {
+	return errors.RuntimeError(nil, "", errors.ErrNativeArgCount, name, expected, got)
+}
+
+// argTypeError reports a native function called with an argument of the wrong type.
+func argTypeError(name string, expected string) error {
+	return errors.RuntimeError(nil, "", errors.ErrNativeArgType, name, expected)
 }
 
 // PrintFunction prints arguments to stdout without newline
-func PrintFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func PrintFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 	for i, arg := range args {
 		if i > 0 {
 			fmt.Print(" ")
@@ -64,11 +86,11 @@ func PrintFunction(args []values.RuntimeValue, scope interface{}) values.Runtime
 		fmt.Print(arg)
 	}
 	// fmt.Print("\n")
-	return &values.NullValue{Type: parser.NodeTypeNull}
+	return &values.NullValue{Type: parser.NodeTypeNull}, nil
 }
 
 // PrintlnFunction prints arguments to stdout with newline
-func PrintlnFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func PrintlnFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 	for i, arg := range args {
 		if i > 0 {
 			fmt.Print(" ")
@@ -76,10 +98,10 @@ func PrintlnFunction(args []values.RuntimeValue, scope interface{}) values.Runti
 		fmt.Print(arg)
 	}
 	fmt.Print("\n")
-	return &values.NullValue{Type: parser.NodeTypeNull}
+	return &values.NullValue{Type: parser.NodeTypeNull}, nil
 }
 
-func InputFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func InputFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 	prompt := ""
 	if len(args) > 0 {
 		prompt = fmt.Sprint(args[0])
@@ -88,200 +110,330 @@ func InputFunction(args []values.RuntimeValue, scope interface{}) values.Runtime
 	reader := bufio.NewReader(os.Stdin)
 	value, err := reader.ReadString('\n')
 	if err != nil {
-		fmt.Printf("Error reading input: %v\n", err)
-		os.Exit(1)
-		return nil
+		return nil, errors.RuntimeError(nil, "", errors.ErrInputFailed, err)
 	}
 	// Trim the newline character but keep the string as is
 	value = strings.TrimSpace(value)
 	return &values.StringValue{Type: parser.NodeTypeString,
 		Value: value,
-	}
+	}, nil
 }
 
-func RandomFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
-	return &values.NumericValue{Type: parser.NodeTypeNumeric,
-		Value: rand.Float64(),
-	}
+func RandomFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	return values.NewFloat(rand.Float64()), nil
 }
 
-func RandIntFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func RandIntFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 
-	if len(args) > 2 {
-		fmt.Printf("RandInt function expects 1 or 2 arguments\n")
-		os.Exit(1)
-		return nil
+	if len(args) != 0 && len(args) != 2 {
+		return nil, argCountError("randInt", "0 or 2 arguments", len(args))
 	}
 
-	var min *values.NumericValue = &values.NumericValue{Type: parser.NodeTypeNumeric, Value: 0}
-	var limit *values.NumericValue = &values.NumericValue{Type: parser.NodeTypeNumeric, Value: 100}
-	var ok bool
-	if len(args) > 1 {
-		min, ok = args[0].(*values.NumericValue)
-		if !ok {
-			fmt.Printf("RandInt function expects a numeric argument\n")
-			os.Exit(1)
-			return nil
+	var min, limit int64 = 0, 100
+	if len(args) == 2 {
+		first, ok := args[0].(*values.NumericValue)
+		if !ok || !first.IsInt {
+			return nil, argTypeError("randInt", "int arguments")
 		}
-		limit, ok = args[1].(*values.NumericValue)
-		if !ok {
-			fmt.Printf("RandInt function expects a numeric argument\n")
-			os.Exit(1)
-			return nil
+		second, ok := args[1].(*values.NumericValue)
+		if !ok || !second.IsInt {
+			return nil, argTypeError("randInt", "int arguments")
 		}
+		min, limit = first.Int, second.Int
 	}
 
-	randomNumber := float64(rand.Intn(int(limit.Value)-int(min.Value)+1) + int(min.Value))
-
-	return &values.NumericValue{Type: parser.NodeTypeNumeric,
-		Value: randomNumber,
+	if min > limit {
+		return nil, errors.RuntimeError(nil, "", errors.ErrRandIntRange, min, limit)
 	}
+	// limit - min + 1 numbers can be picked, unless that count doesn't fit in an int
+	span := limit - min
+	if span < 0 || span == math.MaxInt64 {
+		return nil, errors.RuntimeError(nil, "", errors.ErrRandIntOverflow, min, limit)
+	}
+
+	randomNumber := rand.Int63n(span+1) + min
+
+	return values.NewInt(randomNumber), nil
 }
 
-func AbsFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func AbsFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("abs", "1 argument", len(args))
+	}
 	number, ok := args[0].(*values.NumericValue)
 	if !ok {
-		fmt.Printf("Abs function expects a numeric argument\n")
-		os.Exit(1)
-		return nil
+		return nil, argTypeError("abs", "a numeric argument")
 	}
-	return &values.NumericValue{Type: parser.NodeTypeNumeric,
-		Value: math.Abs(number.Value),
+	if number.IsInt {
+		if number.Int == math.MinInt64 {
+			return nil, errors.RuntimeError(nil, "", errors.ErrIntegerOverflow, fmt.Sprintf("abs(%d)", number.Int))
+		}
+		if number.Int < 0 {
+			return values.NewInt(-number.Int), nil
+		}
+		return number, nil
 	}
+	return values.NewFloat(math.Abs(number.Value)), nil
 }
 
-func RoundFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func RoundFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("round", "1 argument", len(args))
+	}
 	number, ok := args[0].(*values.NumericValue)
 	if !ok {
-		fmt.Printf("Round function expects a numeric argument\n")
-		os.Exit(1)
-		return nil
+		return nil, argTypeError("round", "a numeric argument")
+	}
+	// Rounded floats become ints, unless they are too big for one
+	if number.IsInt {
+		return number, nil
 	}
-	return &values.NumericValue{Type: parser.NodeTypeNumeric,
-		Value: math.Round(number.Value),
+	rounded := math.Round(number.Value)
+	if integer, ok := floatToInt(rounded); ok {
+		return values.NewInt(integer), nil
 	}
+	return values.NewFloat(rounded), nil
 }
 
-func MaxFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
-	number1, ok := args[0].(*values.NumericValue)
-	if !ok {
-		fmt.Printf("Max function expects a numeric argument\n")
-		os.Exit(1)
-		return nil
-	}
-	number2, ok := args[1].(*values.NumericValue)
-	if !ok {
-		fmt.Printf("Max function expects a numeric argument\n")
-		os.Exit(1)
-		return nil
-	}
-	return &values.NumericValue{Type: parser.NodeTypeNumeric,
-		Value: math.Max(number1.Value, number2.Value),
-	}
+func MaxFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	return extremum("max", args, 1)
 }
 
-func MinFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
-	number1, ok := args[0].(*values.NumericValue)
-	if !ok {
-		fmt.Printf("Min function expects a numeric argument\n")
-		os.Exit(1)
-		return nil
-	}
-	number2, ok := args[1].(*values.NumericValue)
-	if !ok {
-		fmt.Printf("Min function expects a numeric argument\n")
-		os.Exit(1)
-		return nil
+func MinFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	return extremum("min", args, -1)
+}
+
+// extremum returns the biggest (sign 1) or the smallest (sign -1) of at least 2 numbers.
+// On ties the first one wins.
+func extremum(name string, args []values.RuntimeValue, sign int) (values.RuntimeValue, error) {
+	if len(args) < 2 {
+		return nil, argCountError(name, "at least 2 arguments", len(args))
 	}
-	return &values.NumericValue{Type: parser.NodeTypeNumeric,
-		Value: math.Min(number1.Value, number2.Value),
+	var result *values.NumericValue
+	for _, arg := range args {
+		number, ok := arg.(*values.NumericValue)
+		if !ok {
+			return nil, argTypeError(name, "numeric arguments")
+		}
+		if result == nil || values.CompareNumbers(number, result)*sign > 0 {
+			result = number
+		}
 	}
+	return result, nil
 }
 
-func LenFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func LenFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 	if len(args) != 1 {
-		fmt.Printf("len() expects 1 argument, got %d\n", len(args))
-		os.Exit(1)
-		return nil
+		return nil, argCountError("len", "1 argument", len(args))
 	}
 
-	// Handle strings
+	// Handle strings, counting characters rather than bytes
 	if strVal, ok := args[0].(*values.StringValue); ok {
-		return &values.NumericValue{
-			Type:  parser.NodeTypeNumeric,
-			Value: float64(len(strVal.Value)),
-		}
+		return values.NewInt(int64(utf8.RuneCountInString(strVal.Value))), nil
 	}
 
 	// Handle arrays
 	if arrVal, ok := args[0].(*values.ArrayValue); ok {
-		return &values.NumericValue{
-			Type:  parser.NodeTypeNumeric,
-			Value: float64(len(arrVal.Elements)),
-		}
+		return values.NewInt(int64(len(arrVal.Elements))), nil
+	}
+
+	// Handle objects
+	if objVal, ok := args[0].(*values.ObjectValue); ok {
+		return values.NewInt(int64(objVal.Len())), nil
 	}
 
-	fmt.Printf("len() expects a string or array argument\n")
-	os.Exit(1)
-	return nil
+	return nil, argTypeError("len", "a string, array or object argument")
 }
 
-func NumberFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func NumberFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("number", "1 argument", len(args))
+	}
 	stringValue, ok := args[0].(*values.StringValue)
 	if !ok {
-		fmt.Printf("Number function expects a string argument\n")
-		os.Exit(1)
-		return nil
+		return nil, argTypeError("number", "a string argument")
+	}
+	// Whole numbers become ints, anything else a float
+	if integer, err := strconv.ParseInt(stringValue.Value, 10, 64); err == nil {
+		return values.NewInt(integer), nil
 	}
 	value, err := strconv.ParseFloat(stringValue.Value, 64)
 	if err != nil {
-		fmt.Printf("Error parsing number: %v\n", err)
-		os.Exit(1)
-		return nil
+		return nil, errors.RuntimeError(nil, "", errors.ErrCannotParseNumber, stringValue.Value)
 	}
-	return &values.NumericValue{Type: parser.NodeTypeNumeric,
-		Value: value,
+	return values.NewFloat(value), nil
+}
+
+// IntFunction converts a number or a string to an int. Floats are truncated towards zero.
+func IntFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("int", "1 argument", len(args))
+	}
+	if stringValue, ok := args[0].(*values.StringValue); ok {
+		integer, err := strconv.ParseInt(stringValue.Value, 10, 64)
+		if err != nil {
+			return nil, errors.RuntimeError(nil, "", errors.ErrCannotParseNumber, stringValue.Value)
+		}
+		return values.NewInt(integer), nil
+	}
+	number, ok := args[0].(*values.NumericValue)
+	if !ok {
+		return nil, argTypeError("int", "a numeric or string argument")
+	}
+	if number.IsInt {
+		return number, nil
+	}
+	integer, ok := floatToInt(math.Trunc(number.Value))
+	if !ok {
+		return nil, errors.RuntimeError(nil, "", errors.ErrIntegerOverflow, fmt.Sprintf("int(%s)", number))
 	}
+	return values.NewInt(integer), nil
 }
 
-func StringFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
-	numberValue, ok := args[0].(*values.NumericValue)
+// FloatFunction converts a number or a string to a float
+func FloatFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("float", "1 argument", len(args))
+	}
+	if stringValue, ok := args[0].(*values.StringValue); ok {
+		value, err := strconv.ParseFloat(stringValue.Value, 64)
+		if err != nil {
+			return nil, errors.RuntimeError(nil, "", errors.ErrCannotParseNumber, stringValue.Value)
+		}
+		return values.NewFloat(value), nil
+	}
+	number, ok := args[0].(*values.NumericValue)
 	if !ok {
-		fmt.Printf("String function expects a numeric argument\n")
-		os.Exit(1)
-		return nil
+		return nil, argTypeError("float", "a numeric or string argument")
 	}
-	return &values.StringValue{Type: parser.NodeTypeString,
-		Value: strconv.FormatFloat(numberValue.Value, 'f', -1, 64),
+	return values.NewFloat(number.Value), nil
+}
+
+// floatToInt converts a whole float to an int, reporting false when it is out of range
+func floatToInt(value float64) (int64, bool) {
+	// -2^63 is exactly representable as a float64, 2^63 is the first value that doesn't fit
+	if math.IsNaN(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(value), true
+}
+
+func StringFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("string", "1 argument", len(args))
 	}
+	numberValue, ok := args[0].(*values.NumericValue)
+	if !ok {
+		return nil, argTypeError("string", "a numeric argument")
+	}
+	// Numbers are formatted the same way they are printed, floats keep their decimal point: 2.0
+	return &values.StringValue{Type: parser.NodeTypeString, Value: numberValue.String()}, nil
 }
 
-func BoolFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func BoolFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("bool", "1 argument", len(args))
+	}
 	boolValue, ok := args[0].(*values.StringValue)
 	if !ok {
-		fmt.Printf("Bool function expects a boolean argument\n")
-		os.Exit(1)
-		return nil
+		return nil, argTypeError("bool", "a string argument")
 	}
-	return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: boolValue.Value == "true"}
+	return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: boolValue.Value == "true"}, nil
 }
 
-func TypeFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func TypeFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("type", "1 argument", len(args))
+	}
 	typeValue := args[0]
+	if number, ok := typeValue.(*values.NumericValue); ok {
+		return &values.StringValue{Type: parser.NodeTypeString, Value: number.TypeName()}, nil
+	}
+	// Instances of user-defined classes report the class name
+	if object, ok := typeValue.(*values.ObjectValue); ok && object.Class != nil {
+		return &values.StringValue{Type: parser.NodeTypeString, Value: object.Class.Name}, nil
+	}
 	return &values.StringValue{
 		Type:  parser.NodeTypeString,
 		Value: strings.ToLower(fmt.Sprint(typeValue.NodeType())),
+	}, nil
+}
+
+// BytesFunction returns the UTF-8 bytes of a string as an array of ints
+func BytesFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("bytes", "1 argument", len(args))
 	}
+	str, ok := args[0].(*values.StringValue)
+	if !ok {
+		return nil, argTypeError("bytes", "a string argument")
+	}
+	elements := make([]values.RuntimeValue, len(str.Value))
+	for i := 0; i < len(str.Value); i++ {
+		elements[i] = values.NewInt(int64(str.Value[i]))
+	}
+	return &values.ArrayValue{Type: parser.NodeTypeArray, Elements: elements}, nil
 }
 
-func SleepFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+// RunesFunction returns the Unicode code points of the characters of a string as an array of ints
+func RunesFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("runes", "1 argument", len(args))
+	}
+	str, ok := args[0].(*values.StringValue)
+	if !ok {
+		return nil, argTypeError("runes", "a string argument")
+	}
+	elements := []values.RuntimeValue{}
+	for _, r := range str.Value {
+		elements = append(elements, values.NewInt(int64(r)))
+	}
+	return &values.ArrayValue{Type: parser.NodeTypeArray, Elements: elements}, nil
+}
+
+// CodepointFunction returns the Unicode code point of a single character: codepoint("A") is 65
+func CodepointFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("codepoint", "1 argument", len(args))
+	}
+	str, ok := args[0].(*values.StringValue)
+	if !ok || utf8.RuneCountInString(str.Value) != 1 {
+		return nil, argTypeError("codepoint", "a string of a single character")
+	}
+	r, _ := utf8.DecodeRuneInString(str.Value)
+	return values.NewInt(int64(r)), nil
+}
+
+// ChrFunction returns the character of a Unicode code point: chr(65) is "A"
+func ChrFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("chr", "1 argument", len(args))
+	}
+	number, ok := args[0].(*values.NumericValue)
+	if !ok || !number.IsInt {
+		return nil, argTypeError("chr", "an int argument")
+	}
+	if number.Int < 0 || number.Int > utf8.MaxRune || !utf8.ValidRune(rune(number.Int)) {
+		return nil, errors.RuntimeError(nil, "", errors.ErrInvalidCodepoint, number.Int)
+	}
+	return &values.StringValue{Type: parser.NodeTypeString, Value: string(rune(number.Int))}, nil
+}
+
+func SleepFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, argCountError("sleep", "1 argument", len(args))
+	}
+	seconds, ok := args[0].(*values.NumericValue)
+	if !ok {
+		return nil, argTypeError("sleep", "a numeric argument")
+	}
 	// Convert seconds to milliseconds to handle decimal values
-	duration := time.Duration(args[0].(*values.NumericValue).Value*1000) * time.Millisecond
+	duration := time.Duration(seconds.Value*1000) * time.Millisecond
 	time.Sleep(duration)
-	return &values.NullValue{Type: parser.NodeTypeNull}
+	return &values.NullValue{Type: parser.NodeTypeNull}, nil
 }
 
-func ClearFunction(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+func ClearFunction(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 	fmt.Printf("\x1b[2J")
-	return &values.NullValue{Type: parser.NodeTypeNull}
+	return &values.NullValue{Type: parser.NodeTypeNull}, nil
 }
@@ -0,0 +1,178 @@
+package builtins
+
+import (
+	"gloob-interpreter/internal/parser"
+	"gloob-interpreter/internal/values"
+)
+
+// ObjectKeysMethod returns an array with the keys of an object, in insertion order
+func ObjectKeysMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 0 {
+				return nil, argCountError("keys", "no arguments", len(args))
+			}
+			keys := make([]values.RuntimeValue, 0, object.Len())
+			for _, key := range object.Keys() {
+				keys = append(keys, &values.StringValue{Type: parser.NodeTypeString, Value: key})
+			}
+			return newArray(keys), nil
+		},
+	}
+}
+
+// ObjectValuesMethod returns an array with the values of an object, in insertion order
+func ObjectValuesMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 0 {
+				return nil, argCountError("values", "no arguments", len(args))
+			}
+			elements := make([]values.RuntimeValue, 0, object.Len())
+			for _, key := range object.Keys() {
+				elements = append(elements, object.Properties[key])
+			}
+			return newArray(elements), nil
+		},
+	}
+}
+
+// ObjectEntriesMethod returns an array of [key, value] pairs, in insertion order
+func ObjectEntriesMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 0 {
+				return nil, argCountError("entries", "no arguments", len(args))
+			}
+			entries := make([]values.RuntimeValue, 0, object.Len())
+			for _, key := range object.Keys() {
+				entries = append(entries, newArray([]values.RuntimeValue{
+					&values.StringValue{Type: parser.NodeTypeString, Value: key},
+					object.Properties[key],
+				}))
+			}
+			return newArray(entries), nil
+		},
+	}
+}
+
+// ObjectHasMethod checks if an object has a property
+func ObjectHasMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 1 {
+				return nil, argCountError("has", "1 argument (key)", len(args))
+			}
+			key, ok := args[0].(*values.StringValue)
+			if !ok {
+				return nil, argTypeError("has", "string key")
+			}
+			return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: object.Has(key.Value)}, nil
+		},
+	}
+}
+
+// ObjectGetMethod returns the value of a property, or a default value (null if not given) when it doesn't exist
+func ObjectGetMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 1 && len(args) != 2 {
+				return nil, argCountError("get", "1 or 2 arguments (key, default)", len(args))
+			}
+			key, ok := args[0].(*values.StringValue)
+			if !ok {
+				return nil, argTypeError("get", "string key")
+			}
+			if value, exists := object.Get(key.Value); exists {
+				return value, nil
+			}
+			if len(args) == 2 {
+				return args[1], nil
+			}
+			return &values.NullValue{Type: parser.NodeTypeNull}, nil
+		},
+	}
+}
+
+// ObjectDeleteMethod removes a property from an object, returns the object
+func ObjectDeleteMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			if len(args) != 1 {
+				return nil, argCountError("delete", "1 argument (key)", len(args))
+			}
+			key, ok := args[0].(*values.StringValue)
+			if !ok {
+				return nil, argTypeError("delete", "string key")
+			}
+			object.Delete(key.Value)
+			return object, nil
+		},
+	}
+}
+
+// ObjectMergeMethod returns a new object with the properties of the object and then those of every argument.
+// Later properties replace earlier ones with the same key.
+func ObjectMergeMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			merged := values.NewObjectValue()
+			for _, key := range object.Keys() {
+				merged.Set(key, object.Properties[key])
+			}
+			for _, arg := range args {
+				other, ok := arg.(*values.ObjectValue)
+				if !ok {
+					return nil, argTypeError("merge", "objects")
+				}
+				for _, key := range other.Keys() {
+					merged.Set(key, other.Properties[key])
+				}
+			}
+			return merged, nil
+		},
+	}
+}
+
+// ObjectLenMethod returns the number of properties of an object
+func ObjectLenMethod(object *values.ObjectValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			return values.NewInt(int64(object.Len())), nil
+		},
+	}
+}
+
+// GetObjectMethod returns the object method with the given name.
+// Unlike arrays and strings, objects also have properties, so an unknown name is not an error here:
+// ok is false and the caller reports the missing property.
+func GetObjectMethod(object *values.ObjectValue, methodName string) (method values.RuntimeValue, ok bool) {
+	switch methodName {
+	case "keys":
+		return ObjectKeysMethod(object), true
+	case "values":
+		return ObjectValuesMethod(object), true
+	case "entries":
+		return ObjectEntriesMethod(object), true
+	case "has":
+		return ObjectHasMethod(object), true
+	case "get":
+		return ObjectGetMethod(object), true
+	case "delete":
+		return ObjectDeleteMethod(object), true
+	case "merge":
+		return ObjectMergeMethod(object), true
+	case "len":
+		return ObjectLenMethod(object), true
+	default:
+		return nil, false
+	}
+}
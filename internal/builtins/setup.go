@@ -2,6 +2,15 @@ package builtins
 
 import "gloob-interpreter/internal/scope"
 
+// NewGlobalScope creates the scope of the global variables of a program.
+// It sits on a scope holding the built-ins, which the modules imported by the program share,
+// so native functions declared in that scope are visible from every file.
+func NewGlobalScope() *scope.Scope {
+	s := scope.NewScope(nil)
+	SetupBuiltins(s)
+	return scope.NewScope(s)
+}
+
 // SetupBuiltins sets up all built-in constants and native functions
 func SetupBuiltins(s *scope.Scope) {
 	SetupConstants(s)
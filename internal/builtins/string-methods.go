@@ -1,22 +1,35 @@
 package builtins
 
 import (
-	"fmt"
+	"gloob-interpreter/internal/errors"
 	"gloob-interpreter/internal/parser"
 	"gloob-interpreter/internal/values"
-	"os"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
-// StringLenMethod returns the length of a string
+// StringLenMethod returns the length of a string in characters
 func StringLenMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
-			return &values.NumericValue{
-				Type:  parser.NodeTypeNumeric,
-				Value: float64(len(str.Value)),
-			}
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			return values.NewInt(int64(utf8.RuneCountInString(str.Value))), nil
+		},
+	}
+}
+
+// StringReverseMethod returns a string with its characters in reverse order
+func StringReverseMethod(str *values.StringValue) *values.NativeFunctionValue {
+	return &values.NativeFunctionValue{
+		Type: parser.NodeTypeNativeFunction,
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+			runes := []rune(str.Value)
+			slices.Reverse(runes)
+			return &values.StringValue{
+				Type:  parser.NodeTypeString,
+				Value: string(runes),
+			}, nil
 		},
 	}
 }
@@ -25,11 +38,11 @@ func StringLenMethod(str *values.StringValue) *values.NativeFunctionValue {
 func StringUpperMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			return &values.StringValue{
 				Type:  parser.NodeTypeString,
 				Value: strings.ToUpper(str.Value),
-			}
+			}, nil
 		},
 	}
 }
@@ -38,11 +51,11 @@ func StringUpperMethod(str *values.StringValue) *values.NativeFunctionValue {
 func StringLowerMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			return &values.StringValue{
 				Type:  parser.NodeTypeString,
 				Value: strings.ToLower(str.Value),
-			}
+			}, nil
 		},
 	}
 }
@@ -51,11 +64,11 @@ func StringLowerMethod(str *values.StringValue) *values.NativeFunctionValue {
 func StringTrimMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			return &values.StringValue{
 				Type:  parser.NodeTypeString,
 				Value: strings.TrimSpace(str.Value),
-			}
+			}, nil
 		},
 	}
 }
@@ -64,22 +77,18 @@ func StringTrimMethod(str *values.StringValue) *values.NativeFunctionValue {
 func StringContainsMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("contains() expects 1 argument, got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("contains", "1 argument", len(args))
 			}
 			if args[0].NodeType() != parser.NodeTypeString {
-				fmt.Printf("contains() expects a string argument\n")
-				os.Exit(1)
-				return nil
+				return nil, argTypeError("contains", "a string argument")
 			}
 			substring := args[0].(*values.StringValue).Value
 			return &values.BooleanValue{
 				Type:  parser.NodeTypeBoolean,
 				Value: strings.Contains(str.Value, substring),
-			}
+			}, nil
 		},
 	}
 }
@@ -88,16 +97,12 @@ func StringContainsMethod(str *values.StringValue) *values.NativeFunctionValue {
 func StringSplitMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("split() expects 1 argument (separator), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("split", "1 argument (separator)", len(args))
 			}
 			if args[0].NodeType() != parser.NodeTypeString {
-				fmt.Printf("split() expects a string separator\n")
-				os.Exit(1)
-				return nil
+				return nil, argTypeError("split", "a string separator")
 			}
 			separator := args[0].(*values.StringValue).Value
 			parts := strings.Split(str.Value, separator)
@@ -114,7 +119,7 @@ func StringSplitMethod(str *values.StringValue) *values.NativeFunctionValue {
 			return &values.ArrayValue{
 				Type:     parser.NodeTypeArray,
 				Elements: elements,
-			}
+			}, nil
 		},
 	}
 }
@@ -123,23 +128,19 @@ func StringSplitMethod(str *values.StringValue) *values.NativeFunctionValue {
 func StringReplaceMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 2 {
-				fmt.Printf("replace() expects 2 arguments (old, new), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("replace", "2 arguments (old, new)", len(args))
 			}
 			if args[0].NodeType() != parser.NodeTypeString || args[1].NodeType() != parser.NodeTypeString {
-				fmt.Printf("replace() expects string arguments\n")
-				os.Exit(1)
-				return nil
+				return nil, argTypeError("replace", "string arguments")
 			}
 			oldStr := args[0].(*values.StringValue).Value
 			newStr := args[1].(*values.StringValue).Value
 			return &values.StringValue{
 				Type:  parser.NodeTypeString,
 				Value: strings.ReplaceAll(str.Value, oldStr, newStr),
-			}
+			}, nil
 		},
 	}
 }
@@ -149,57 +150,50 @@ func StringReplaceMethod(str *values.StringValue) *values.NativeFunctionValue {
 func StringIndexOfMethod(str *values.StringValue) *values.NativeFunctionValue {
 	return &values.NativeFunctionValue{
 		Type: parser.NodeTypeNativeFunction,
-		Expression: func(args []values.RuntimeValue, scope interface{}) values.RuntimeValue {
+		Expression: func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
 			if len(args) != 1 {
-				fmt.Printf("indexOf() expects 1 argument (substring), got %d\n", len(args))
-				os.Exit(1)
-				return nil
+				return nil, argCountError("indexOf", "1 argument (substring)", len(args))
 			}
 			if args[0].NodeType() != parser.NodeTypeString {
-				fmt.Printf("indexOf() expects a string argument\n")
-				os.Exit(1)
-				return nil
+				return nil, argTypeError("indexOf", "a string argument")
 			}
 			substring := args[0].(*values.StringValue).Value
 			index := strings.Index(str.Value, substring)
 
-			// Convert 0-based to 1-based (or 0 if not found)
+			// Convert the byte offset to a 1-based character index (or 0 if not found)
 			if index == -1 {
 				index = 0
 			} else {
-				index = index + 1
+				index = utf8.RuneCountInString(str.Value[:index]) + 1
 			}
 
-			return &values.NumericValue{
-				Type:  parser.NodeTypeNumeric,
-				Value: float64(index),
-			}
+			return values.NewInt(int64(index)), nil
 		},
 	}
 }
 
 // GetStringMethod returns the appropriate string method as a native function
-func GetStringMethod(str *values.StringValue, methodName string) values.RuntimeValue {
+func GetStringMethod(str *values.StringValue, methodName string) (values.RuntimeValue, error) {
 	switch methodName {
 	case "len":
-		return StringLenMethod(str)
+		return StringLenMethod(str), nil
 	case "upper":
-		return StringUpperMethod(str)
+		return StringUpperMethod(str), nil
 	case "lower":
-		return StringLowerMethod(str)
+		return StringLowerMethod(str), nil
 	case "trim":
-		return StringTrimMethod(str)
+		return StringTrimMethod(str), nil
 	case "contains":
-		return StringContainsMethod(str)
+		return StringContainsMethod(str), nil
 	case "split":
-		return StringSplitMethod(str)
+		return StringSplitMethod(str), nil
 	case "replace":
-		return StringReplaceMethod(str)
+		return StringReplaceMethod(str), nil
 	case "indexOf":
-		return StringIndexOfMethod(str)
+		return StringIndexOfMethod(str), nil
+	case "reverse":
+		return StringReverseMethod(str), nil
 	default:
-		fmt.Printf("Unknown string method: %s\n", methodName)
-		os.Exit(1)
-		return nil
+		return nil, errors.RuntimeError(nil, "", errors.ErrUnknownStringMethod, methodName)
 	}
 }
@@ -1,126 +1,414 @@
 package errors
 
 import (
+	goerrors "errors"
 	"fmt"
 	"gloob-interpreter/internal/colors"
 	"gloob-interpreter/internal/lexer"
-	"os"
+	"io"
 	"strings"
 )
 
-// Error message constants for parser (syntax errors)
+// Kind identifies what went wrong in a Gloob program.
+// Every kind has a name (used by hosts and scripts to tell errors apart)
+// and a message template that is formatted with the error arguments.
+type Kind int
+
+// Error kinds for parser (syntax errors)
 const (
-	ErrExpectedIdentifier      = "An identifier was expected here dude 😎"
-	ErrExpectedEqual           = "An equal sign was expected here dude 😎"
-	ErrExpectedColon           = "A colon was expected after the key :v"
-	ErrExpectedOpenCurly       = "Expected opening curly brackets"
-	ErrExpectedCloseCurly      = "Expected closing curly brackets"
-	ErrExpectedOpenParen       = "Expected opening parentheses"
-	ErrExpectedCloseParen      = "Expected closing parentheses"
-	ErrExpectedCloseSquare     = "Expected closing square brackets"
-	ErrExpectedFunctionName    = "Expected function name"
-	ErrConstMustHaveValue      = "A constant declaration must have a value 🤔"
-	ErrExpectedIdentifierParam = "Expected an identifier here 👀"
-	ErrUnexpectedToken         = "Unexpected token '%s'. Are you sure you typed it correctly? 🤔"
+	ErrExpectedIdentifier Kind = iota
+	ErrExpectedEqual
+	ErrExpectedColon
+	ErrExpectedOpenCurly
+	ErrExpectedCloseCurly
+	ErrExpectedOpenParen
+	ErrExpectedCloseParen
+	ErrExpectedCloseSquare
+	ErrExpectedFunctionName
+	ErrConstMustHaveValue
+	ErrExpectedIdentifierParam
+	ErrUnexpectedToken
+	ErrExpectedImportPath
+	ErrExpectedFrom
+	ErrInvalidNumberLiteral
+	ErrNumberLiteralOutOfRange
+	ErrExpectedCatchOrFinally
+	ErrBreakOutsideLoop
+	ErrContinueOutsideLoop
+	ErrReturnOutsideFunction
+	ErrUnknownLoopLabel
+	ErrDuplicateLoopLabel
+	ErrExpectedArrow
+	ErrRangeLoopSingleVariable
+	ErrExpectedMatchArrow
+	ErrInvalidPattern
+	ErrExpectedClassName
+	ErrDuplicateClassMember
+	ErrRestParameterNotLast
+	ErrRestParameterDefault
+	ErrPositionalAfterNamed
+	ErrRestElementNotLast
+	ErrNotTopLevel
+	ErrExpectedImport
+	ErrInvalidExport
 )
 
-// Error message constants for runtime (interpreter errors)
+// Error kinds for runtime (interpreter errors)
 const (
-	ErrVariableNotFound           = "Variable '%s' not found. Are you sure you typed it correctly? 🤔"
-	ErrVariableAlreadyDeclared    = "Variable '%s' already declared"
-	ErrVariableNotInitialized     = "Variable '%s' is not initialized. Are you sure you declared it? 🤔"
-	ErrConstantCannotBeAssigned   = "Constant '%s' cannot be assigned to because it is, how can i say it to you? It is a constant 😒"
-	ErrDivisionByZero             = "You know you cannot divide by zero, what are you trying to prove? 😒"
-	ErrUnknownOperator            = "Unknown operator: '%s', i don't know what to tell you 🫣"
-	ErrUnknownOperatorWithString  = "Unknown operator: '%s', with string operands"
-	ErrInvalidOperandTypes        = "Invalid operand types for binary expression: %s %s %s"
-	ErrInvalidLeftOperand         = "Invalid left operand type for binary expression: %s"
-	ErrInvalidRightOperand        = "Invalid right operand type for binary expression: %s"
-	ErrCannotAccessProperty       = "Cannot access property '%s' on non-object type: %s"
-	ErrPropertyNotFound           = "Property '%s' not found on object"
-	ErrCannotAssignProperty       = "Cannot assign property '%s' on non-object type: %s"
-	ErrCannotIndexNonArray        = "Cannot index non-array type: %s"
-	ErrIndexMustBeNumeric         = "Index must be numeric"
-	ErrArrayIndexOutOfBounds      = "Array index out of bounds: %d (array length: %d)"
-	ErrStringIndexOutOfBounds     = "String index out of bounds: %d (string length: %d)"
-	ErrCannotIndexType            = "Cannot index type: %s"
-	ErrInvalidNativeFunction      = "Invalid native function type"
-	ErrFunctionArgCountMismatch   = "Function '%s' expects %d arguments, got %d"
-	ErrCannotCallNonFunction      = "Cannot call non-function value: %s"
-	ErrUnknownNodeType            = "Unknown node type: '%s', i don't know what to tell you 🫣"
-	ErrRangeLoopNeedsNumeric      = "Range loop requires numeric values for 'from' and 'to'"
-	ErrRangeLoopIncrementNumeric  = "Range loop increment must be numeric"
-	ErrForEachNeedsArray          = "For-each loop requires an array, got %s"
-	ErrCannotCompareTypes         = "Cannot compare %s and %s with operator %s"
-	ErrUnknownComparisonOperator  = "Unknown comparison operator: %s"
-	ErrUnknownLogicalOperator     = "Unknown logical operator: %s"
-	ErrCannotUseOperatorWithNull  = "Cannot use operator %s with null values"
-	ErrInvalidIdentifierForAssign = "Invalid identifier type for variable assignment: %s"
+	ErrVariableNotFound Kind = iota + 100
+	ErrVariableAlreadyDeclared
+	ErrVariableNotInitialized
+	ErrConstantCannotBeAssigned
+	ErrDivisionByZero
+	ErrIntegerOverflow
+	ErrUnknownOperator
+	ErrUnknownOperatorWithString
+	ErrInvalidOperandTypes
+	ErrInvalidLeftOperand
+	ErrInvalidRightOperand
+	ErrInvalidUnaryOperand
+	ErrCannotAccessProperty
+	ErrPropertyNotFound
+	ErrCannotAssignProperty
+	ErrCannotIndexNonArray
+	ErrIndexMustBeNumeric
+	ErrKeyMustBeString
+	ErrArrayIndexOutOfBounds
+	ErrStringIndexOutOfBounds
+	ErrCannotIndexType
+	ErrInvalidNativeFunction
+	ErrFunctionArgCountMismatch
+	ErrCannotCallNonFunction
+	ErrUnknownNodeType
+	ErrRangeLoopNeedsNumeric
+	ErrRangeLoopIncrementNumeric
+	ErrForEachNeedsArray
+	ErrCannotCompareTypes
+	ErrUnknownComparisonOperator
+	ErrUnknownLogicalOperator
+	ErrCannotUseOperatorWithNull
+	ErrInvalidIdentifierForAssign
+	ErrNativeArgCount
+	ErrNativeArgType
+	ErrPopFromEmptyArray
+	ErrReduceEmptyArray
+	ErrUnknownArrayMethod
+	ErrUnknownStringMethod
+	ErrInputFailed
+	ErrCannotParseNumber
+	ErrThrown
+	ErrNoMatchingPattern
+	ErrTooManyConstructorArgs
+	ErrIsNeedsClass
+	ErrMissingArgument
+	ErrUnknownNamedArgument
+	ErrDuplicateArgument
+	ErrSpreadNeedsArray
+	ErrNamedArgumentsNotSupported
+	ErrImportFailed
+	ErrCircularImport
+	ErrNotExported
+	ErrInvalidCodepoint
+	ErrRangeNeedsInt
+	ErrRangeStepZero
+	ErrDestructuringMismatch
+	ErrNegativeRepeatCount
+	ErrRepeatTooLarge
+	ErrRandIntRange
+	ErrRandIntOverflow
+	ErrMaxCallDepth
+	ErrIndexMustBeInt
+	ErrRepeatCountNeedsInt
+	ErrCannotConvert
 )
 
-// SyntaxError prints a detailed syntax error with file context and exits.
-func SyntaxError(token lexer.Token, sourceCode string, message string) {
-	// Print the error header with file location
-	fmt.Printf("\n%s %s\n", colors.Red("Syntax Error:"), message)
+// kindInfo holds the name and the message template of a Kind.
+type kindInfo struct {
+	name    string
+	message string
+}
+
+var kinds = map[Kind]kindInfo{
+	// Syntax errors
+	ErrExpectedIdentifier:      {"ExpectedIdentifier", "An identifier was expected here dude 😎"},
+	ErrExpectedEqual:           {"ExpectedEqual", "An equal sign was expected here dude 😎"},
+	ErrExpectedColon:           {"ExpectedColon", "A colon was expected after the key :v"},
+	ErrExpectedOpenCurly:       {"ExpectedOpenCurly", "Expected opening curly brackets"},
+	ErrExpectedCloseCurly:      {"ExpectedCloseCurly", "Expected closing curly brackets"},
+	ErrExpectedOpenParen:       {"ExpectedOpenParen", "Expected opening parentheses"},
+	ErrExpectedCloseParen:      {"ExpectedCloseParen", "Expected closing parentheses"},
+	ErrExpectedCloseSquare:     {"ExpectedCloseSquare", "Expected closing square brackets"},
+	ErrExpectedFunctionName:    {"ExpectedFunctionName", "Expected function name"},
+	ErrConstMustHaveValue:      {"ConstMustHaveValue", "A constant declaration must have a value 🤔"},
+	ErrExpectedIdentifierParam: {"ExpectedIdentifierParam", "Expected an identifier here 👀"},
+	ErrUnexpectedToken:         {"UnexpectedToken", "Unexpected token '%s'. Are you sure you typed it correctly? 🤔"},
+	ErrExpectedImportPath:      {"ExpectedImportPath", "Expected string path after import"},
+	ErrExpectedFrom:            {"ExpectedFrom", "Expected 'from' after loop variable"},
+	ErrInvalidNumberLiteral:    {"InvalidNumberLiteral", "Invalid number literal '%s'"},
+	ErrNumberLiteralOutOfRange: {"NumberLiteralOutOfRange", "Number literal '%s' is too big: ints go up to 9223372036854775807 and floats up to about 1.8e308"},
+	ErrExpectedCatchOrFinally:  {"ExpectedCatchOrFinally", "A try block needs a catch or a finally clause 🤔"},
+	ErrBreakOutsideLoop:        {"BreakOutsideLoop", "'break' can only be used inside a loop 🤔"},
+	ErrContinueOutsideLoop:     {"ContinueOutsideLoop", "'continue' can only be used inside a loop 🤔"},
+	ErrReturnOutsideFunction:   {"ReturnOutsideFunction", "'return' can only be used inside a function 🤔"},
+	ErrUnknownLoopLabel:        {"UnknownLoopLabel", "There is no enclosing loop labeled '%s'"},
+	ErrDuplicateLoopLabel:      {"DuplicateLoopLabel", "Loop label '%s' is already used by an enclosing loop"},
+	ErrExpectedArrow:           {"ExpectedArrow", "Expected '=>' after the parameters of an arrow function"},
+	ErrRangeLoopSingleVariable: {"RangeLoopSingleVariable", "A range loop takes a single variable, got '%s, %s'"},
+	ErrExpectedMatchArrow:      {"ExpectedMatchArrow", "Expected '=>' after the pattern of a match arm"},
+	ErrInvalidPattern:          {"InvalidPattern", "'%s' can't be used as a pattern. Patterns are literals, names, _, arrays and objects 🤔"},
+	ErrExpectedClassName:       {"ExpectedClassName", "Expected class name"},
+	ErrDuplicateClassMember:    {"DuplicateClassMember", "'%s' is declared twice in class %s"},
+	ErrRestParameterNotLast:    {"RestParameterNotLast", "The rest parameter '...%s' must be the last one"},
+	ErrRestParameterDefault:    {"RestParameterDefault", "A rest parameter can't have a default value, it is [] when there are no arguments left"},
+	ErrPositionalAfterNamed:    {"PositionalAfterNamed", "Positional arguments must come before named arguments"},
+	ErrRestElementNotLast:      {"RestElementNotLast", "'...%s' must be the last element of the pattern"},
+	ErrNotTopLevel:             {"NotTopLevel", "'%s' can only be used at the top level of a file, outside of any block"},
+	ErrExpectedImport:          {"ExpectedImport", "Expected 'import' after the module path, as in from \"utils\" import helper"},
+	ErrInvalidExport:           {"InvalidExport", "Only var, const, function and class declarations can be exported"},
 
-	if token.Filename != "" {
-		fmt.Printf("%s  at %s:%d:%d\n", colors.Blue("-->"), token.Filename, token.Line, token.ColumnStart)
-	} else {
-		fmt.Printf("%s  at line %d, column %d\n", colors.Blue("-->"), token.Line, token.ColumnStart)
+	// Runtime errors
+	ErrVariableNotFound:           {"VariableNotFound", "Variable '%s' not found. Are you sure you typed it correctly? 🤔"},
+	ErrVariableAlreadyDeclared:    {"VariableAlreadyDeclared", "Variable '%s' already declared"},
+	ErrVariableNotInitialized:     {"VariableNotInitialized", "Variable '%s' is not initialized. Are you sure you declared it? 🤔"},
+	ErrConstantCannotBeAssigned:   {"ConstantCannotBeAssigned", "Constant '%s' cannot be assigned to because it is, how can i say it to you? It is a constant 😒"},
+	ErrDivisionByZero:             {"DivisionByZero", "You know you cannot divide by zero, what are you trying to prove? 😒"},
+	ErrIntegerOverflow:            {"IntegerOverflow", "Integer overflow: %s doesn't fit in an int, use floats (e.g. 1.0) for bigger numbers 😬"},
+	ErrUnknownOperator:            {"UnknownOperator", "Unknown operator: '%s', i don't know what to tell you 🫣"},
+	ErrUnknownOperatorWithString:  {"UnknownOperatorWithString", "Unknown operator: '%s', with string operands"},
+	ErrInvalidOperandTypes:        {"InvalidOperandTypes", "Invalid operand types for binary expression: %s %s %s"},
+	ErrInvalidLeftOperand:         {"InvalidLeftOperand", "Invalid left operand type for binary expression: %s"},
+	ErrInvalidRightOperand:        {"InvalidRightOperand", "Invalid right operand type for binary expression: %s"},
+	ErrInvalidUnaryOperand:        {"InvalidUnaryOperand", "Invalid operand type for unary '%s': %s"},
+	ErrCannotAccessProperty:       {"CannotAccessProperty", "Cannot access property '%s' on non-object type: %s"},
+	ErrPropertyNotFound:           {"PropertyNotFound", "Property '%s' not found on object"},
+	ErrCannotAssignProperty:       {"CannotAssignProperty", "Cannot assign property '%s' on non-object type: %s"},
+	ErrCannotIndexNonArray:        {"CannotIndexNonArray", "Cannot index non-array type: %s"},
+	ErrIndexMustBeNumeric:         {"IndexMustBeNumeric", "Index must be numeric"},
+	ErrKeyMustBeString:            {"KeyMustBeString", "Object keys must be strings, got %s"},
+	ErrArrayIndexOutOfBounds:      {"ArrayIndexOutOfBounds", "Array index out of bounds: %d (array length: %d)"},
+	ErrStringIndexOutOfBounds:     {"StringIndexOutOfBounds", "String index out of bounds: %d (string length: %d)"},
+	ErrCannotIndexType:            {"CannotIndexType", "Cannot index type: %s"},
+	ErrInvalidNativeFunction:      {"InvalidNativeFunction", "Invalid native function type"},
+	ErrFunctionArgCountMismatch:   {"FunctionArgCountMismatch", "Function '%s' expects %s, got %d"},
+	ErrCannotCallNonFunction:      {"CannotCallNonFunction", "Cannot call non-function value: %s"},
+	ErrUnknownNodeType:            {"UnknownNodeType", "Unknown node type: '%s', i don't know what to tell you 🫣"},
+	ErrRangeLoopNeedsNumeric:      {"RangeLoopNeedsNumeric", "Range loop requires numeric values for 'from' and 'to'"},
+	ErrRangeLoopIncrementNumeric:  {"RangeLoopIncrementNumeric", "Range loop increment must be numeric"},
+	ErrForEachNeedsArray:          {"ForEachNeedsArray", "For-each loop requires an array, an object or a range, got %s"},
+	ErrCannotCompareTypes:         {"CannotCompareTypes", "Cannot compare %s and %s with operator %s"},
+	ErrUnknownComparisonOperator:  {"UnknownComparisonOperator", "Unknown comparison operator: %s"},
+	ErrUnknownLogicalOperator:     {"UnknownLogicalOperator", "Unknown logical operator: %s"},
+	ErrCannotUseOperatorWithNull:  {"CannotUseOperatorWithNull", "Cannot use operator %s with null values"},
+	ErrInvalidIdentifierForAssign: {"InvalidIdentifierForAssign", "Invalid identifier type for variable assignment: %s"},
+	ErrNativeArgCount:             {"NativeArgCount", "%s() expects %s, got %d"},
+	ErrNativeArgType:              {"NativeArgType", "%s() expects %s"},
+	ErrPopFromEmptyArray:          {"PopFromEmptyArray", "Cannot pop from empty array"},
+	ErrReduceEmptyArray:           {"ReduceEmptyArray", "Cannot reduce an empty array without an initial value"},
+	ErrUnknownArrayMethod:         {"UnknownArrayMethod", "Unknown array method: %s"},
+	ErrUnknownStringMethod:        {"UnknownStringMethod", "Unknown string method: %s"},
+	ErrInputFailed:                {"InputFailed", "Error reading input: %v"},
+	ErrCannotParseNumber:          {"CannotParseNumber", "Cannot convert '%s' to a number 🤔"},
+	ErrThrown:                     {"Error", "%s"},
+	ErrNoMatchingPattern:          {"NoMatchingPattern", "No pattern matched the value %s, add a '_ => ...' arm to handle everything else 🤷"},
+	ErrTooManyConstructorArgs:     {"TooManyConstructorArgs", "%s() takes at most %d arguments (one for each field), got %d"},
+	ErrIsNeedsClass:               {"IsNeedsClass", "The right side of 'is' must be a class, got %s"},
+	ErrMissingArgument:            {"MissingArgument", "%s() is missing the argument '%s'"},
+	ErrUnknownNamedArgument:       {"UnknownNamedArgument", "%s() has no parameter named '%s'"},
+	ErrDuplicateArgument:          {"DuplicateArgument", "Argument '%s' of %s() is given twice"},
+	ErrSpreadNeedsArray:           {"SpreadNeedsArray", "Only arrays can be spread with ..., got %s"},
+	ErrNamedArgumentsNotSupported: {"NamedArgumentsNotSupported", "Named arguments can only be passed to Gloob functions and classes, not to built-in functions"},
+	ErrImportFailed:               {"ImportFailed", "Cannot import '%s': %v"},
+	ErrCircularImport:             {"CircularImport", "Circular import of '%s': %s"},
+	ErrNotExported:                {"NotExported", "'%s' doesn't export '%s'"},
+	ErrInvalidCodepoint:           {"InvalidCodepoint", "%d is not a valid Unicode code point"},
+	ErrRangeNeedsInt:              {"RangeNeedsInt", "Ranges are made of ints, got %s"},
+	ErrRangeStepZero:              {"RangeStepZero", "The step of a range can't be 0"},
+	ErrDestructuringMismatch:      {"DestructuringMismatch", "Cannot destructure %s, it doesn't have the shape of the pattern"},
+	ErrNegativeRepeatCount:        {"NegativeRepeatCount", "Cannot repeat a string %v times"},
+	ErrRepeatTooLarge:             {"RepeatTooLarge", "Repeating a string %v times would make it too large"},
+	ErrRandIntRange:               {"RandIntRange", "randInt() expects min <= max, got %d and %d"},
+	ErrRandIntOverflow:            {"RandIntOverflow", "randInt() cannot pick a number from %d to %d, the range is too large"},
+	ErrMaxCallDepth:               {"MaxCallDepth", "Maximum call depth of %d exceeded, is there an infinite recursion? 🌀"},
+	ErrIndexMustBeInt:             {"IndexMustBeInt", "Indexes are ints, got %s"},
+	ErrRepeatCountNeedsInt:        {"RepeatCountNeedsInt", "A string can only be repeated an int number of times, got %s"},
+	ErrCannotConvert:              {"CannotConvert", "Cannot convert %s"},
+}
+
+// String returns the name of the kind, e.g. "DivisionByZero".
+func (k Kind) String() string {
+	if info, ok := kinds[k]; ok {
+		return info.name
 	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
 
-	// Get the line from source code
-	lines := strings.Split(sourceCode, "\n")
-	if token.Line > 0 && token.Line <= len(lines) {
-		lineContent := lines[token.Line-1]
+// Message formats the message template of the kind with the given arguments.
+func (k Kind) Message(args ...interface{}) string {
+	info, ok := kinds[k]
+	if !ok {
+		return k.String()
+	}
+	if len(args) == 0 {
+		return info.message
+	}
+	return fmt.Sprintf(info.message, args...)
+}
 
-		// Print line number and content
-		fmt.Printf("%s\n", colors.Blue(fmt.Sprintf("   %d | ", token.Line)))
-		fmt.Printf("   %d | %s\n", token.Line, lineContent)
+// Phase tells whether an error was found while parsing or while running a program.
+type Phase string
 
-		// Print the pointer to the error location
-		padding := strings.Repeat(" ", token.ColumnStart-1)
-		underline := strings.Repeat("^", max(1, token.ColumnEnd-token.ColumnStart+1))
-		fmt.Printf("%s %s%s\n", colors.Blue("     |"), padding, colors.Red(underline))
+const (
+	PhaseSyntax  Phase = "Syntax Error"
+	PhaseRuntime Phase = "Runtime Error"
+)
+
+// Error is a structured Gloob error.
+// It keeps everything needed to report the error to the user
+// and lets Go hosts inspect what went wrong.
+type Error struct {
+	Phase      Phase        // Whether it is a syntax or a runtime error
+	Kind       Kind         // What went wrong
+	Message    string       // Human readable message
+	Token      *lexer.Token // Where it went wrong (nil when unknown)
+	SourceLine string       // The offending source line, used for reporting
+	Value      interface{}  // The value passed to a throw statement (nil for other errors)
+	CustomKind string       // Kind name given by a thrown object, overrides Kind.String()
+}
+
+// Error implements the error interface.
+// The position is prefixed in the usual file:line:column form when it is known.
+func (e *Error) Error() string {
+	if e.Token == nil {
+		return e.Message
+	}
+	if e.Token.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Token.Filename, e.Token.Line, e.Token.ColumnStart, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Token.Line, e.Token.ColumnStart, e.Message)
+}
+
+// KindName returns the name of the error kind as seen by Gloob scripts.
+func (e *Error) KindName() string {
+	if e.CustomKind != "" {
+		return e.CustomKind
+	}
+	return e.Kind.String()
+}
+
+// Filename returns the file the error comes from, or "" when unknown.
+func (e *Error) Filename() string {
+	if e.Token == nil {
+		return ""
 	}
+	return e.Token.Filename
+}
 
-	fmt.Println()
-	os.Exit(1)
+// Line returns the line the error comes from, or 0 when unknown.
+func (e *Error) Line() int {
+	if e.Token == nil {
+		return 0
+	}
+	return e.Token.Line
 }
 
-// RuntimeError prints a detailed runtime error with file context if available and exits.
-func RuntimeError(token *lexer.Token, sourceCode string, message string) {
+// Column returns the column the error comes from, or 0 when unknown.
+func (e *Error) Column() int {
+	if e.Token == nil {
+		return 0
+	}
+	return e.Token.ColumnStart
+}
+
+// SyntaxError creates a syntax error located at token.
+func SyntaxError(token lexer.Token, sourceCode string, kind Kind, args ...interface{}) *Error {
+	return &Error{
+		Phase:      PhaseSyntax,
+		Kind:       kind,
+		Message:    kind.Message(args...),
+		Token:      &token,
+		SourceLine: sourceLine(sourceCode, &token),
+	}
+}
+
+// RuntimeError creates a runtime error. The token can be nil when the
+// position is not known yet; Locate can fill it in later.
+func RuntimeError(token *lexer.Token, sourceCode string, kind Kind, args ...interface{}) *Error {
+	return &Error{
+		Phase:      PhaseRuntime,
+		Kind:       kind,
+		Message:    kind.Message(args...),
+		Token:      token,
+		SourceLine: sourceLine(sourceCode, token),
+	}
+}
+
+// Locate attaches a position to a Gloob error that doesn't have one yet.
+// Errors that already know where they happened, and non-Gloob errors, are returned untouched.
+func Locate(err error, token *lexer.Token, sourceCode string) error {
+	gloobErr, ok := err.(*Error)
+	if !ok || gloobErr.Token != nil || token == nil {
+		return err
+	}
+	gloobErr.Token = token
+	gloobErr.SourceLine = sourceLine(sourceCode, token)
+	return gloobErr
+}
+
+// From returns the Gloob error carried by err.
+// Errors that don't come from Gloob are wrapped as runtime errors of the generic "Error" kind.
+func From(err error) *Error {
+	var gloobErr *Error
+	if goerrors.As(err, &gloobErr) {
+		return gloobErr
+	}
+	return RuntimeError(nil, "", ErrThrown, err.Error())
+}
+
+// Report prints a detailed error with file context to w.
+// Errors that are not Gloob errors are printed as plain messages.
+func Report(w io.Writer, err error) {
+	var gloobErr *Error
+	if !goerrors.As(err, &gloobErr) {
+		fmt.Fprintf(w, "\n%s %s\n\n", colors.Red("Error:"), err)
+		return
+	}
+
 	// Print the error header
-	fmt.Printf("\n%s %s\n", colors.Red("Runtime Error:"), message)
+	fmt.Fprintf(w, "\n%s %s\n", colors.Red(string(gloobErr.Phase)+":"), gloobErr.Message)
 
 	// If we have token information, show file location
-	if token != nil {
+	if token := gloobErr.Token; token != nil {
 		if token.Filename != "" {
-			fmt.Printf("%s  at %s:%d:%d\n", colors.Blue("-->"), token.Filename, token.Line, token.ColumnStart)
+			fmt.Fprintf(w, "%s  at %s:%d:%d\n", colors.Blue("-->"), token.Filename, token.Line, token.ColumnStart)
 		} else {
-			fmt.Printf("%s  at line %d, column %d\n", colors.Blue("-->"), token.Line, token.ColumnStart)
+			fmt.Fprintf(w, "%s  at line %d, column %d\n", colors.Blue("-->"), token.Line, token.ColumnStart)
 		}
 
-		// Get the line from source code if available
-		if sourceCode != "" {
-			lines := strings.Split(sourceCode, "\n")
-			if token.Line > 0 && token.Line <= len(lines) {
-				lineContent := lines[token.Line-1]
-
-				// Print line number and content
-				fmt.Printf("%s\n", colors.Blue(fmt.Sprintf("   %d | ", token.Line)))
-				fmt.Printf("   %d | %s\n", token.Line, lineContent)
-
-				// Print the pointer to the error location
-				padding := strings.Repeat(" ", token.ColumnStart-1)
-				underline := strings.Repeat("^", max(1, token.ColumnEnd-token.ColumnStart+1))
-				fmt.Printf("%s %s%s\n", colors.Blue("     |"), padding, colors.Red(underline))
-			}
+		if gloobErr.SourceLine != "" {
+			// Print line number and content
+			fmt.Fprintf(w, "%s\n", colors.Blue(fmt.Sprintf("   %d | ", token.Line)))
+			fmt.Fprintf(w, "   %d | %s\n", token.Line, gloobErr.SourceLine)
+
+			// Print the pointer to the error location
+			padding := strings.Repeat(" ", max(0, token.ColumnStart-1))
+			underline := strings.Repeat("^", max(1, token.ColumnEnd-token.ColumnStart+1))
+			fmt.Fprintf(w, "%s %s%s\n", colors.Blue("     |"), padding, colors.Red(underline))
 		}
 	}
 
-	fmt.Println()
-	os.Exit(1)
+	fmt.Fprintln(w)
+}
+
+// sourceLine returns the line of sourceCode pointed by token, or "" if it is not available.
+func sourceLine(sourceCode string, token *lexer.Token) string {
+	if token == nil || sourceCode == "" {
+		return ""
+	}
+	lines := strings.Split(sourceCode, "\n")
+	if token.Line > 0 && token.Line <= len(lines) {
+		return lines[token.Line-1]
+	}
+	return ""
 }
 
 // Helper function to get max of two integers
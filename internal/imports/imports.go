@@ -1,116 +1,193 @@
 package imports
 
 import (
+	"embed"
 	"fmt"
+	"gloob-interpreter/internal/errors"
 	"gloob-interpreter/internal/parser"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
-// ProcessImports processes all import statements recursively and returns
-// a flattened list of statements with imports resolved.
-// It handles circular import detection and relative path resolution.
-func ProcessImports(program *parser.Program, basePath string) (*parser.Program, error) {
-	visited := make(map[string]bool)
+// ProjectMarker is the file marking the root directory of a project.
+// Modules can be imported by their path from the project root, from any file of the project.
+const ProjectMarker = "gloob.project"
 
-	// Get the directory of the base file
-	baseDir := filepath.Dir(basePath)
-	if baseDir == "" {
-		baseDir = "."
-	}
+// stdPrefix is the prefix of the modules of the standard library, such as std/json.
+const stdPrefix = "std/"
 
-	// Process the program recursively
-	statements, err := processStatementsWithImports(program.Statements, baseDir, visited)
-	if err != nil {
-		return nil, err
-	}
+// stdlib holds the modules of the standard library, embedded into the binary.
+//
+//go:embed std/*.gloob
+var stdlib embed.FS
 
-	return &parser.Program{Statements: statements}, nil
+// searchPath lists the directories searched for modules that aren't found
+// next to the importing file or in its project.
+var searchPath = filepath.SplitList(os.Getenv("GLOOB_PATH"))
+
+// AddSearchPath adds directories to search for modules, before the ones already listed
+// (the ones of the GLOOB_PATH environment variable).
+func AddSearchPath(dirs ...string) {
+	searchPath = append(append([]string{}, dirs...), searchPath...)
 }
 
-// processStatementsWithImports recursively processes a list of statements,
-// expanding any import statements into the imported file's statements.
-func processStatementsWithImports(statements []parser.Statement, baseDir string, visited map[string]bool) ([]parser.Statement, error) {
-	result := make([]parser.Statement, 0)
+// Module identifies a file imported by a Gloob program.
+type Module struct {
+	Path string // Path used to read the file and to report errors
+	Key  string // Absolute path, the same for every import of the file (std/... for the standard library)
+	Std  bool   // Whether the module belongs to the standard library embedded into the binary
+}
 
-	for _, stmt := range statements {
-		// Check if this is an import statement
-		if importStmt, ok := stmt.(*parser.ImportStatement); ok {
-			// Resolve the import path
-			importPath := resolveImportPath(importStmt.Path, baseDir)
+// Resolve finds the module imported with importPath by the file importer.
+// If the path doesn't have a .gloob extension, it adds one. Then:
+//   - std/... paths are modules of the standard library
+//   - absolute paths are used as they are
+//   - ./ and ../ paths are relative to the directory of the importer
+//   - other paths are looked up next to the importer, then from the root of its project
+//     (the closest directory holding a gloob.project file) and then in the search path
+func Resolve(importPath string, importer string) (Module, error) {
+	// Add .gloob extension if not present
+	if !strings.HasSuffix(importPath, ".gloob") && !strings.HasSuffix(importPath, ".gb") {
+		importPath += ".gloob"
+	}
 
-			// Check for circular imports
-			absPath, err := filepath.Abs(importPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to resolve import path %s: %v", importPath, err)
-			}
+	if strings.HasPrefix(importPath, stdPrefix) {
+		if _, err := fs.Stat(stdlib, importPath); err != nil {
+			return Module{}, fmt.Errorf("there is no module %s in the standard library", strings.TrimSuffix(importPath, path.Ext(importPath)))
+		}
+		return Module{Path: importPath, Key: importPath, Std: true}, nil
+	}
 
-			if visited[absPath] {
-				return nil, fmt.Errorf("circular import detected: %s", importPath)
-			}
+	// The modules of the standard library import each other from the standard library too
+	if importer, ok := strings.CutPrefix(importer, stdPrefix); ok {
+		return Resolve(stdPrefix+path.Join(path.Dir(importer), importPath), "")
+	}
 
-			// Mark this file as visited
-			visited[absPath] = true
+	candidates := []string{importPath}
+	if !filepath.IsAbs(importPath) {
+		importerDir := filepath.Dir(importer)
+		candidates = []string{filepath.Join(importerDir, importPath)}
+		if !strings.HasPrefix(importPath, "./") && !strings.HasPrefix(importPath, "../") {
+			if root, ok := projectRoot(importerDir); ok {
+				candidates = append(candidates, filepath.Join(root, importPath))
+			}
+			for _, dir := range searchPath {
+				candidates = append(candidates, filepath.Join(dir, importPath))
+			}
+		}
+	}
 
-			// Read and parse the imported file
-			importedStatements, err := loadAndParseFile(importPath, visited)
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			key, err := Key(candidate)
 			if err != nil {
-				return nil, fmt.Errorf("failed to import %s: %v", importStmt.Path, err)
+				return Module{}, err
 			}
-
-			// Add the imported statements to the result
-			result = append(result, importedStatements...)
-
-			// Unmark after processing (to allow the same file to be imported in different branches)
-			// Comment this out if you want to prevent importing the same file multiple times
-			// delete(visited, absPath)
-		} else {
-			// Not an import statement, add it as-is
-			result = append(result, stmt)
+			return Module{Path: candidate, Key: key}, nil
 		}
 	}
-
-	return result, nil
+	return Module{}, fmt.Errorf("file not found, looked for %s", strings.Join(candidates, ", "))
 }
 
-// resolveImportPath resolves an import path relative to the base directory.
-// If the path doesn't have a .gloob extension, it adds one.
-func resolveImportPath(importPath, baseDir string) string {
-	// Add .gloob extension if not present
-	if !strings.HasSuffix(importPath, ".gloob") && !strings.HasSuffix(importPath, ".gb") {
-		importPath += ".gloob"
+// Key returns the key identifying the module read from filename, see Module.Key.
+func Key(filename string) (string, error) {
+	if strings.HasPrefix(filename, stdPrefix) {
+		return filename, nil
 	}
+	return filepath.Abs(filename)
+}
 
-	// If the path is absolute, use it as-is
-	if filepath.IsAbs(importPath) {
-		return importPath
+// projectRoot returns the closest directory, starting from dir and going up, that holds a project marker.
+func projectRoot(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ProjectMarker)); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
 	}
-
-	// Otherwise, resolve it relative to the base directory
-	return filepath.Join(baseDir, importPath)
 }
 
-// loadAndParseFile loads a file, parses it, and recursively processes its imports.
-func loadAndParseFile(filePath string, visited map[string]bool) ([]parser.Statement, error) {
-	// Read the file
-	sourceCode, err := os.ReadFile(filePath)
+// Load reads and parses a module, returning its program and its source code.
+// Syntax errors in the module are returned as they are, as *errors.Error values.
+func Load(module Module) (*parser.Program, string, error) {
+	var sourceCode []byte
+	var err error
+	if module.Std {
+		sourceCode, err = stdlib.ReadFile(module.Path)
+	} else {
+		sourceCode, err = os.ReadFile(module.Path)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
+		return nil, "", err
 	}
 
-	// Parse the file
 	p := parser.NewParser(nil)
-	program := p.ProduceASTWithFilename(string(sourceCode), filePath)
+	program, err := p.ProduceASTWithFilename(string(sourceCode), module.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	return program, string(sourceCode), nil
+}
+
+// Check loads every module imported by a program, directly or not, without evaluating anything.
+// It reports the first module that can't be read or parsed, and circular imports.
+func Check(program *parser.Program, sourceCode string, filename string) error {
+	key, _ := Key(filename)
+	return check(program, sourceCode, Module{Path: filename, Key: key}, nil, make(map[string]bool))
+}
 
-	// Get the directory of this file for resolving its imports
-	fileDir := filepath.Dir(filePath)
+// check checks the imports of a module. importers holds the modules being checked, from the
+// main program to the one importing this module, and checked the modules already checked.
+func check(program *parser.Program, sourceCode string, module Module, importers []Module, checked map[string]bool) error {
+	importers = append(importers, module)
 
-	// Process imports recursively
-	statements, err := processStatementsWithImports(program.Statements, fileDir, visited)
-	if err != nil {
-		return nil, err
+	for _, statement := range program.Statements {
+		importStatement, ok := statement.(*parser.ImportStatement)
+		if !ok {
+			continue
+		}
+
+		imported, err := Resolve(importStatement.Path, module.Path)
+		if err != nil {
+			return errors.RuntimeError(importStatement.Token, sourceCode, errors.ErrImportFailed, importStatement.Path, err)
+		}
+		if checked[imported.Key] {
+			continue
+		}
+		for i, importer := range importers {
+			if importer.Key == imported.Key {
+				var chain []string
+				for _, importer := range importers[i:] {
+					chain = append(chain, importer.Path)
+				}
+				chain = append(chain, imported.Path)
+				return errors.RuntimeError(importStatement.Token, sourceCode, errors.ErrCircularImport, importStatement.Path, strings.Join(chain, " -> "))
+			}
+		}
+
+		importedProgram, importedSource, err := Load(imported)
+		if err != nil {
+			if _, ok := err.(*errors.Error); ok {
+				return err
+			}
+			return errors.RuntimeError(importStatement.Token, sourceCode, errors.ErrImportFailed, importStatement.Path, err)
+		}
+		if err := check(importedProgram, importedSource, imported, importers, checked); err != nil {
+			return err
+		}
 	}
 
-	return statements, nil
+	checked[module.Key] = true
+	return nil
 }
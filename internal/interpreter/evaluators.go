@@ -1,285 +1,639 @@
 package interpreter
 
 import (
+	"cmp"
 	"fmt"
 	"gloob-interpreter/internal/builtins"
-	"gloob-interpreter/internal/colors"
+	"gloob-interpreter/internal/errors"
+	"gloob-interpreter/internal/imports"
+	"gloob-interpreter/internal/lexer"
 	"gloob-interpreter/internal/parser"
 	"gloob-interpreter/internal/scope"
 	"gloob-interpreter/internal/values"
-	"os"
+	"math"
+	"slices"
 	"strings"
 )
 
-func evaluateBinaryExpression(node *parser.BinaryExpression, s *scope.Scope) values.RuntimeValue {
-	left := Evaluate(node.Left, s)
-	right := Evaluate(node.Right, s)
+func evaluateBinaryExpression(node *parser.BinaryExpression, s *scope.Scope) (values.RuntimeValue, error) {
+	left, err := Evaluate(node.Left, s)
+	if err != nil {
+		return nil, err
+	}
+	// Logical operators only evaluate the right operand when the left one doesn't decide the result
+	if isLogicalOperator(node.Operator) {
+		return evaluateLogicalExpression(node, left, s)
+	}
+	right, err := Evaluate(node.Right, s)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateBinaryOperation(node, left, right, s)
+}
+
+// evaluateBinaryOperation applies the operator of node to already evaluated operands.
+func evaluateBinaryOperation(node *parser.BinaryExpression, left values.RuntimeValue, right values.RuntimeValue, s *scope.Scope) (values.RuntimeValue, error) {
+	if node.Operator == "is" {
+		return evaluateIsExpression(node, left, right, s)
+	}
 
 	// Handle comparison operators
 	if isComparisonOperator(node.Operator) {
-		return evaluateComparisonExpression(node.Operator, left, right, s)
+		return evaluateComparisonExpression(node, left, right, s)
 	}
 
 	if left.NodeType() == parser.NodeTypeString && node.Operator == "*" && right.NodeType() == parser.NodeTypeNumeric {
-		return evaluateStringMultiplication(left.(*values.StringValue), right.(*values.NumericValue), s)
+		return evaluateStringMultiplication(node, left.(*values.StringValue), right.(*values.NumericValue), s)
 	}
 
 	if left.NodeType() == parser.NodeTypeString || right.NodeType() == parser.NodeTypeString {
-		return evaluateStringBinaryExpression(node.Operator, left, right, s)
+		return evaluateStringBinaryExpression(node, left, right, s)
 	}
 
 	if left.NodeType() != parser.NodeTypeNumeric || right.NodeType() != parser.NodeTypeNumeric {
-		fmt.Printf("Invalid operand types for binary expression: %s %s %s\n", left.NodeType(), node.Operator, right.NodeType())
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrInvalidOperandTypes, left.NodeType(), node.Operator, right.NodeType())
 	}
 
 	leftNumeric, ok := left.(*values.NumericValue)
 	if !ok {
-		fmt.Printf("Invalid left operand type for binary expression: %s\n", left.NodeType())
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrInvalidLeftOperand, left.NodeType())
 	}
 	rightNumeric, ok := right.(*values.NumericValue)
 	if !ok {
-		fmt.Printf("Invalid right operand type for binary expression: %s\n", right.NodeType())
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrInvalidRightOperand, right.NodeType())
+	}
+	return evaluateNumericBinaryExpression(node, leftNumeric, rightNumeric, s)
+}
+
+// evaluateUnaryExpression applies ! (logical not, using truthiness) and - (numeric negation).
+func evaluateUnaryExpression(node *parser.UnaryExpression, s *scope.Scope) (values.RuntimeValue, error) {
+	operand, err := Evaluate(node.Operand, s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.Operator {
+	case "!":
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: !values.IsTruthy(operand)}, nil
+	case "-":
+		numeric, ok := operand.(*values.NumericValue)
+		if !ok {
+			return nil, runtimeError(s, node.Token, errors.ErrInvalidUnaryOperand, node.Operator, operand.NodeType())
+		}
+		if numeric.IsInt {
+			if numeric.Int == math.MinInt64 {
+				return nil, runtimeError(s, node.Token, errors.ErrIntegerOverflow, fmt.Sprintf("-(%d)", numeric.Int))
+			}
+			return values.NewInt(-numeric.Int), nil
+		}
+		return values.NewFloat(-numeric.Value), nil
 	}
-	return evaluateNumericBinaryExpression(node.Operator, leftNumeric, rightNumeric, s)
+	return nil, runtimeError(s, node.Token, errors.ErrUnknownOperator, node.Operator)
 }
 
-func evaluateStringMultiplication(left *values.StringValue, right *values.NumericValue, s *scope.Scope) values.RuntimeValue {
-	return &values.StringValue{Type: parser.NodeTypeString, Value: strings.Repeat(left.Value, int(right.Value))}
+// evaluateTemplateString evaluates the embedded expressions of a template string
+// in the current scope and joins them with the text around them.
+func evaluateTemplateString(node *parser.TemplateString, s *scope.Scope) (values.RuntimeValue, error) {
+	var result strings.Builder
+	for _, part := range node.Parts {
+		value, err := Evaluate(part, s)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(&result, value)
+	}
+	return &values.StringValue{Type: parser.NodeTypeString, Value: result.String()}, nil
 }
 
-func evaluateStringBinaryExpression(operator string, left values.RuntimeValue, right values.RuntimeValue, s *scope.Scope) values.RuntimeValue {
-	switch operator {
+// maxRepeatLength is the length in bytes of the longest string a repetition can build.
+const maxRepeatLength = 1 << 30
+
+func evaluateStringMultiplication(node *parser.BinaryExpression, left *values.StringValue, right *values.NumericValue, s *scope.Scope) (values.RuntimeValue, error) {
+	if !right.IsInt {
+		return nil, runtimeError(s, node.Token, errors.ErrRepeatCountNeedsInt, right)
+	}
+	if right.Int < 0 {
+		return nil, runtimeError(s, node.Token, errors.ErrNegativeRepeatCount, right.Int)
+	}
+	if left.Value == "" {
+		return left, nil
+	}
+	if right.Int > int64(maxRepeatLength/len(left.Value)) {
+		return nil, runtimeError(s, node.Token, errors.ErrRepeatTooLarge, right.Int)
+	}
+	return &values.StringValue{Type: parser.NodeTypeString, Value: strings.Repeat(left.Value, int(right.Int))}, nil
+}
+
+func evaluateStringBinaryExpression(node *parser.BinaryExpression, left values.RuntimeValue, right values.RuntimeValue, s *scope.Scope) (values.RuntimeValue, error) {
+	switch node.Operator {
 	case "+":
-		return &values.StringValue{Type: parser.NodeTypeString, Value: fmt.Sprintf("%v%v", left, right)}
+		return &values.StringValue{Type: parser.NodeTypeString, Value: fmt.Sprintf("%v%v", left, right)}, nil
 	}
-	fmt.Printf("Unknown operator: %s, with string operands\n", colors.Red(operator))
-	os.Exit(1)
-	return nil
+	return nil, runtimeError(s, node.Token, errors.ErrUnknownOperatorWithString, node.Operator)
 }
 
-func evaluateNumericBinaryExpression(operator string, left *values.NumericValue, right *values.NumericValue, s *scope.Scope) values.RuntimeValue {
-	switch operator {
+// evaluateNumericBinaryExpression does arithmetic on two numbers.
+// Two ints give an int, anything involving a float gives a float.
+func evaluateNumericBinaryExpression(node *parser.BinaryExpression, left *values.NumericValue, right *values.NumericValue, s *scope.Scope) (values.RuntimeValue, error) {
+	if left.IsInt && right.IsInt {
+		return evaluateIntegerBinaryExpression(node, left.Int, right.Int, s)
+	}
+
+	switch node.Operator {
 	case "+":
-		return &values.NumericValue{Type: parser.NodeTypeNumeric, Value: left.Value + right.Value}
+		return values.NewFloat(left.Value + right.Value), nil
 	case "-":
-		return &values.NumericValue{Type: parser.NodeTypeNumeric, Value: left.Value - right.Value}
+		return values.NewFloat(left.Value - right.Value), nil
 	case "*":
-		return &values.NumericValue{Type: parser.NodeTypeNumeric, Value: left.Value * right.Value}
+		return values.NewFloat(left.Value * right.Value), nil
 	case "/":
 		if right.Value == 0 {
-			fmt.Printf("You know you cannot divide by zero, what are you trying to prove? 😒 \n")
-			os.Exit(1)
-			return nil
+			return nil, runtimeError(s, node.Token, errors.ErrDivisionByZero)
 		}
-		return &values.NumericValue{Type: parser.NodeTypeNumeric, Value: left.Value / right.Value}
+		return values.NewFloat(left.Value / right.Value), nil
 	case "%":
-		return &values.NumericValue{Type: parser.NodeTypeNumeric, Value: float64(int(left.Value) % int(right.Value))}
+		if right.Value == 0 {
+			return nil, runtimeError(s, node.Token, errors.ErrDivisionByZero)
+		}
+		return values.NewFloat(math.Mod(left.Value, right.Value)), nil
 
 	}
-	fmt.Printf("Unknown operator: %s, i don't know what to tell you 🫣\n", colors.Red(operator))
-	os.Exit(1)
-	return nil
+	return nil, runtimeError(s, node.Token, errors.ErrUnknownOperator, node.Operator)
+}
+
+// evaluateIntegerBinaryExpression does exact int arithmetic.
+// Division truncates towards zero and % takes the sign of the left operand, like in Go.
+// Results that don't fit in 64 bits are an IntegerOverflow error instead of wrapping around.
+func evaluateIntegerBinaryExpression(node *parser.BinaryExpression, left int64, right int64, s *scope.Scope) (values.RuntimeValue, error) {
+	var result int64
+	overflow := false
+
+	switch node.Operator {
+	case "+":
+		result = left + right
+		overflow = (result > left) != (right > 0)
+	case "-":
+		result = left - right
+		overflow = (result < left) != (right > 0)
+	case "*":
+		result = left * right
+		overflow = left != 0 && (result/left != right || (left == -1 && right == math.MinInt64))
+	case "/":
+		if right == 0 {
+			return nil, runtimeError(s, node.Token, errors.ErrDivisionByZero)
+		}
+		result = left / right
+		overflow = left == math.MinInt64 && right == -1
+	case "%":
+		if right == 0 {
+			return nil, runtimeError(s, node.Token, errors.ErrDivisionByZero)
+		}
+		result = left % right
+	default:
+		return nil, runtimeError(s, node.Token, errors.ErrUnknownOperator, node.Operator)
+	}
+
+	if overflow {
+		return nil, runtimeError(s, node.Token, errors.ErrIntegerOverflow, fmt.Sprintf("%d %s %d", left, node.Operator, right))
+	}
+	return values.NewInt(result), nil
 }
 
-func evaluateProgram(program *parser.Program, s *scope.Scope) values.RuntimeValue {
+func evaluateProgram(program *parser.Program, s *scope.Scope) (values.RuntimeValue, error) {
+	// A program is marked as being evaluated while it runs, so a module importing it back is a
+	// circular import. Imported modules were already marked by importModule, so Start fails for them
+	if key, err := imports.Key(program.Filename); program.Filename != "" && err == nil {
+		modules := s.Modules()
+		if _, ok := modules.Start(key, program.Filename); ok {
+			defer modules.Finish(key, nil)
+		}
+	}
 
 	var lastEvaluated values.RuntimeValue = nil
 
 	for _, statement := range program.Statements {
-		lastEvaluated = Evaluate(statement, s)
+		result, err := Evaluate(statement, s)
+		if err != nil {
+			return nil, err
+		}
+		lastEvaluated = result
 	}
 
-	return lastEvaluated
+	return lastEvaluated, nil
 }
 
-func evaluateIdentifier(node *parser.Identifier, s *scope.Scope) values.RuntimeValue {
-	return s.Get(node.Name)
+func evaluateIdentifier(node *parser.Identifier, s *scope.Scope) (values.RuntimeValue, error) {
+	return s.GetWithToken(node.Name, node.Token)
 }
 
-func evaluateVariableDeclaration(node *parser.VariableDeclaration, isConstant bool, s *scope.Scope) values.RuntimeValue {
+func evaluateVariableDeclaration(node *parser.VariableDeclaration, isConstant bool, s *scope.Scope) (values.RuntimeValue, error) {
 
 	var value values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
 	if node.Value != nil {
-		value = Evaluate(node.Value, s)
+		var err error
+		value, err = Evaluate(node.Value, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if node.Pattern != nil {
+		bindings, err := destructure(node.Pattern, value, node.Token, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, binding := range bindings {
+			if _, err := s.Declare(binding.name, binding.value, isConstant); err != nil {
+				return nil, locate(err, binding.token, s)
+			}
+		}
+		return &values.NodeVariableDeclaration{
+			Type:  node.NodeType(),
+			Name:  fmt.Sprint(node.Pattern),
+			Value: value,
+		}, nil
+	}
+	if _, err := s.Declare(node.Identifier, value, isConstant); err != nil {
+		return nil, locate(err, node.Token, s)
 	}
-	s.Declare(node.Identifier, value, isConstant)
 	return &values.NodeVariableDeclaration{
 		Type:  node.NodeType(),
 		Name:  node.Identifier,
 		Value: value,
+	}, nil
+}
+
+func evaluateVariableAssignment(node *parser.VariableAssignmentExpression, s *scope.Scope) (values.RuntimeValue, error) {
+	// Destructuring assignment: [a, b] = [b, a]
+	// The value is evaluated before any variable is assigned, that's what makes the swap work
+	switch node.Identifier.(type) {
+	case *parser.Array, *parser.Object:
+		value, err := Evaluate(node.Value, s)
+		if err != nil {
+			return nil, err
+		}
+		bindings, err := destructure(node.Identifier, value, node.Token, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, binding := range bindings {
+			if _, err := s.Assign(binding.name, binding.value); err != nil {
+				return nil, locate(err, binding.token, s)
+			}
+		}
+		return value, nil
+	}
+
+	// The target is resolved once, so in arr[next()] += 1 next() is only called once
+	target, err := resolveTarget(node.Identifier, node.Token, s)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := Evaluate(node.Value, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compound assignments (+=, -=, *=, /=, %=) combine the current value with the new one
+	if node.Operator != "" && node.Operator != "=" {
+		current, err := target.get()
+		if err != nil {
+			return nil, err
+		}
+		operation := &parser.BinaryExpression{
+			Type:     parser.NodeTypeBinaryExpression,
+			Operator: strings.TrimSuffix(node.Operator, "="),
+			Token:    node.Token,
+		}
+		value, err = evaluateBinaryOperation(operation, current, value, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := target.set(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// evaluateUpdateExpression applies ++ and -- to a variable, an object property or an array element.
+func evaluateUpdateExpression(node *parser.UpdateExpression, s *scope.Scope) (values.RuntimeValue, error) {
+	target, err := resolveTarget(node.Target, node.Token, s)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := target.get()
+	if err != nil {
+		return nil, err
+	}
+	if current.NodeType() != parser.NodeTypeNumeric {
+		return nil, runtimeError(s, node.Token, errors.ErrInvalidUnaryOperand, node.Operator, current.NodeType())
+	}
+
+	operation := &parser.BinaryExpression{
+		Type:     parser.NodeTypeBinaryExpression,
+		Operator: node.Operator[:1],
+		Token:    node.Token,
+	}
+	updated, err := evaluateBinaryOperation(operation, current, values.NewInt(1), s)
+	if err != nil {
+		return nil, err
 	}
+	if err := target.set(updated); err != nil {
+		return nil, err
+	}
+
+	// ++x gives the new value, x++ the old one
+	if node.Prefix {
+		return updated, nil
+	}
+	return current, nil
+}
+
+// assignmentTarget is a place values can be assigned to: a variable, an object property or an array element.
+// The object and the index of the place are evaluated once, when the target is resolved.
+type assignmentTarget struct {
+	get func() (values.RuntimeValue, error)
+	set func(value values.RuntimeValue) error
 }
 
-func evaluateVariableAssignment(node *parser.VariableAssignmentExpression, s *scope.Scope) values.RuntimeValue {
-	if node.Identifier.NodeType() == parser.NodeTypeIdentifier {
+// resolveTarget evaluates the parts of an assignment target, everything but the final read or write.
+// Errors about targets that can't be assigned to are reported at token, the assignment operator.
+func resolveTarget(node parser.Expression, token *lexer.Token, s *scope.Scope) (*assignmentTarget, error) {
+	switch node := node.(type) {
+	case *parser.Identifier:
 		// Regular variable assignment
-		identifier := node.Identifier.(*parser.Identifier)
-		value := Evaluate(node.Value, s)
-		s.Assign(identifier.Name, value)
-		return value
-	} else if node.Identifier.NodeType() == parser.NodeTypeMemberAccess {
+		return &assignmentTarget{
+			get: func() (values.RuntimeValue, error) {
+				return evaluateIdentifier(node, s)
+			},
+			set: func(value values.RuntimeValue) error {
+				_, err := s.Assign(node.Name, value)
+				return locate(err, node.Token, s)
+			},
+		}, nil
+	case *parser.MemberAccess:
 		// Member access assignment (e.g., obj.property = value)
-		return evaluateMemberAccessAssignment(node.Identifier.(*parser.MemberAccess), node.Value, s)
-	} else if node.Identifier.NodeType() == parser.NodeTypeArrayIndex {
+		return resolveMemberAccessTarget(node, s)
+	case *parser.ArrayIndex:
 		// Array index assignment (e.g., arr[1] = value)
-		return evaluateArrayIndexAssignment(node.Identifier.(*parser.ArrayIndex), node.Value, s)
-	} else {
-		fmt.Printf("Invalid identifier type for variable assignment: %s\n", node.Identifier.NodeType())
-		os.Exit(1)
-		return nil
+		return resolveArrayIndexTarget(node, s)
 	}
+	return nil, runtimeError(s, token, errors.ErrInvalidIdentifierForAssign, node.NodeType())
 }
 
-func evaluateObject(node *parser.Object, s *scope.Scope) values.RuntimeValue {
-	properties := make(map[string]values.RuntimeValue)
+func evaluateObject(node *parser.Object, s *scope.Scope) (values.RuntimeValue, error) {
+	object := values.NewObjectValue()
 
 	for _, property := range node.Properties {
-		value := Evaluate(property.Value, s)
-		properties[property.Key] = value
+		value, err := Evaluate(property.Value, s)
+		if err != nil {
+			return nil, err
+		}
+		object.Set(property.Key, value)
 	}
 
-	return &values.ObjectValue{
-		Type:       parser.NodeTypeObject,
-		Properties: properties,
+	return object, nil
+}
+
+// optionalChainNull is returned by an optional link that finds null.
+// It unwinds the rest of the chain up to evaluateOptionalChain, which turns it into null.
+type optionalChainNull struct{}
+
+func (optionalChainNull) Error() string {
+	return "optional chain found null"
+}
+
+// evaluateOptionalChain evaluates a chain with optional links: user?.address.city
+// is null when user is null, instead of failing on the .city access.
+func evaluateOptionalChain(node *parser.OptionalChain, s *scope.Scope) (values.RuntimeValue, error) {
+	value, err := Evaluate(node.Expression, s)
+	if _, ok := err.(optionalChainNull); ok {
+		return &values.NullValue{Type: parser.NodeTypeNull}, nil
 	}
+	return value, err
 }
 
-func evaluateMemberAccess(node *parser.MemberAccess, s *scope.Scope) values.RuntimeValue {
-	object := Evaluate(node.Object, s)
+func evaluateMemberAccess(node *parser.MemberAccess, s *scope.Scope) (values.RuntimeValue, error) {
+	object, err := Evaluate(node.Object, s)
+	if err != nil {
+		return nil, err
+	}
+	if node.Optional && object.NodeType() == parser.NodeTypeNull {
+		return nil, optionalChainNull{}
+	}
 
 	// Handle array methods
 	if object.NodeType() == parser.NodeTypeArray {
-		return builtins.GetArrayMethod(object.(*values.ArrayValue), node.Property)
+		method, err := builtins.GetArrayMethod(object.(*values.ArrayValue), node.Property)
+		return method, locate(err, node.Token, s)
 	}
 
 	// Handle string methods
 	if object.NodeType() == parser.NodeTypeString {
-		return builtins.GetStringMethod(object.(*values.StringValue), node.Property)
+		method, err := builtins.GetStringMethod(object.(*values.StringValue), node.Property)
+		return method, locate(err, node.Token, s)
 	}
 
 	// Handle object properties
 	if object.NodeType() != parser.NodeTypeObject {
-		fmt.Printf("Cannot access property '%s' on non-object type: %s\n", node.Property, object.NodeType())
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrCannotAccessProperty, node.Property, object.NodeType())
 	}
 
+	// Properties win over object methods, so { keys: 1 }.keys is 1
 	objValue := object.(*values.ObjectValue)
-	if value, exists := objValue.Properties[node.Property]; exists {
-		return value
+	if value, exists := objValue.Get(node.Property); exists {
+		return value, nil
 	}
 
-	fmt.Printf("Property '%s' not found on object\n", node.Property)
-	os.Exit(1)
-	return nil
+	// Class methods come next, bound to the instance they are accessed on
+	if objValue.Class != nil {
+		if method, ok := objValue.Class.Methods[node.Property]; ok {
+			return bindMethod(method, objValue), nil
+		}
+	}
+
+	if method, ok := builtins.GetObjectMethod(objValue, node.Property); ok {
+		return method, nil
+	}
+
+	return nil, runtimeError(s, node.Token, errors.ErrPropertyNotFound, node.Property)
 }
 
-func evaluateMemberAccessAssignment(node *parser.MemberAccess, value parser.Expression, s *scope.Scope) values.RuntimeValue {
-	object := Evaluate(node.Object, s)
+func resolveMemberAccessTarget(node *parser.MemberAccess, s *scope.Scope) (*assignmentTarget, error) {
+	object, err := Evaluate(node.Object, s)
+	if err != nil {
+		return nil, err
+	}
 
 	if object.NodeType() != parser.NodeTypeObject {
-		fmt.Printf("Cannot assign property '%s' on non-object type: %s\n", node.Property, object.NodeType())
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrCannotAssignProperty, node.Property, object.NodeType())
 	}
 
 	objValue := object.(*values.ObjectValue)
-	assignedValue := Evaluate(value, s)
-	objValue.Properties[node.Property] = assignedValue
-
-	return assignedValue
+	return &assignmentTarget{
+		get: func() (values.RuntimeValue, error) {
+			if value, exists := objValue.Get(node.Property); exists {
+				return value, nil
+			}
+			return nil, runtimeError(s, node.Token, errors.ErrPropertyNotFound, node.Property)
+		},
+		set: func(value values.RuntimeValue) error {
+			objValue.Set(node.Property, value)
+			return nil
+		},
+	}, nil
 }
 
-func evaluateArrayIndexAssignment(node *parser.ArrayIndex, value parser.Expression, s *scope.Scope) values.RuntimeValue {
+func resolveArrayIndexTarget(node *parser.ArrayIndex, s *scope.Scope) (*assignmentTarget, error) {
 	// Evaluate the array expression
-	arrayValue := Evaluate(node.ArrayExpression, s)
+	arrayValue, err := Evaluate(node.ArrayExpression, s)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if it's actually an array
-	if arrayValue.NodeType() != parser.NodeTypeArray {
-		fmt.Printf("Cannot index non-array type: %s\n", arrayValue.NodeType())
-		os.Exit(1)
-		return nil
+	// Check if it's actually an array or an object
+	if arrayValue.NodeType() != parser.NodeTypeArray && arrayValue.NodeType() != parser.NodeTypeObject {
+		return nil, runtimeError(s, node.Token, errors.ErrCannotIndexNonArray, arrayValue.NodeType())
 	}
 
 	// Evaluate the index
-	indexValue := Evaluate(node.Index, s)
+	indexValue, err := Evaluate(node.Index, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle object keys: obj["dynamic key"] = value
+	if object, ok := arrayValue.(*values.ObjectValue); ok {
+		key, ok := indexValue.(*values.StringValue)
+		if !ok {
+			return nil, runtimeError(s, node.Token, errors.ErrKeyMustBeString, indexValue.NodeType())
+		}
+		return &assignmentTarget{
+			get: func() (values.RuntimeValue, error) {
+				if value, exists := object.Get(key.Value); exists {
+					return value, nil
+				}
+				return nil, runtimeError(s, node.Token, errors.ErrPropertyNotFound, key.Value)
+			},
+			set: func(value values.RuntimeValue) error {
+				object.Set(key.Value, value)
+				return nil
+			},
+		}, nil
+	}
 	if indexValue.NodeType() != parser.NodeTypeNumeric {
-		fmt.Printf("Array index must be numeric\n")
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrIndexMustBeNumeric)
+	}
+	if !indexValue.(*values.NumericValue).IsInt {
+		return nil, runtimeError(s, node.Token, errors.ErrIndexMustBeInt, indexValue)
 	}
 
 	array := arrayValue.(*values.ArrayValue)
-	index := int(indexValue.(*values.NumericValue).Value)
-
-	// Arrays are 1-based in Gloob, convert to 0-based
-	index = index - 1
+	position := int(indexValue.(*values.NumericValue).Int)
 
-	// Check bounds
-	if index < 0 || index >= len(array.Elements) {
-		fmt.Printf("Array index out of bounds: %d (array length: %d)\n", index+1, len(array.Elements))
-		os.Exit(1)
+	// Check bounds when the element is used, the array may have changed in between
+	var index int
+	checkBounds := func() error {
+		index = zeroBasedIndex(position, len(array.Elements))
+		if index < 0 || index >= len(array.Elements) {
+			return runtimeError(s, node.Token, errors.ErrArrayIndexOutOfBounds, position, len(array.Elements))
+		}
 		return nil
 	}
-
-	// Assign the value
-	assignedValue := Evaluate(value, s)
-	array.Elements[index] = assignedValue
-
-	return assignedValue
+	return &assignmentTarget{
+		get: func() (values.RuntimeValue, error) {
+			if err := checkBounds(); err != nil {
+				return nil, err
+			}
+			return array.Elements[index], nil
+		},
+		set: func(value values.RuntimeValue) error {
+			if err := checkBounds(); err != nil {
+				return err
+			}
+			array.Elements[index] = value
+			return nil
+		},
+	}, nil
 }
 
-func evaluateArray(node *parser.Array, s *scope.Scope) values.RuntimeValue {
+func evaluateArray(node *parser.Array, s *scope.Scope) (values.RuntimeValue, error) {
 	elements := make([]values.RuntimeValue, len(node.Elements))
 
 	for i, element := range node.Elements {
-		elements[i] = Evaluate(element, s)
+		value, err := Evaluate(element, s)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = value
 	}
 
 	return &values.ArrayValue{
 		Type:     parser.NodeTypeArray,
 		Elements: elements,
-	}
+	}, nil
 }
 
-func evaluateArrayIndex(node *parser.ArrayIndex, s *scope.Scope) values.RuntimeValue {
+func evaluateArrayIndex(node *parser.ArrayIndex, s *scope.Scope) (values.RuntimeValue, error) {
 	// Evaluate the expression (could be array or string)
-	value := Evaluate(node.ArrayExpression, s)
+	value, err := Evaluate(node.ArrayExpression, s)
+	if err != nil {
+		return nil, err
+	}
+	if node.Optional && value.NodeType() == parser.NodeTypeNull {
+		return nil, optionalChainNull{}
+	}
 
 	// Evaluate the index
-	indexValue := Evaluate(node.Index, s)
+	indexValue, err := Evaluate(node.Index, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle object keys: obj["dynamic key"]
+	if object, ok := value.(*values.ObjectValue); ok {
+		key, ok := indexValue.(*values.StringValue)
+		if !ok {
+			return nil, runtimeError(s, node.Token, errors.ErrKeyMustBeString, indexValue.NodeType())
+		}
+		if property, exists := object.Get(key.Value); exists {
+			return property, nil
+		}
+		return nil, runtimeError(s, node.Token, errors.ErrPropertyNotFound, key.Value)
+	}
+
+	// Handle slices: arr[2..4], text[3..]
+	if indexRange, ok := indexValue.(*values.RangeValue); ok {
+		return evaluateSlice(node, value, indexRange, s)
+	}
+
 	if indexValue.NodeType() != parser.NodeTypeNumeric {
-		fmt.Printf("Index must be numeric\n")
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrIndexMustBeNumeric)
+	}
+	if !indexValue.(*values.NumericValue).IsInt {
+		return nil, runtimeError(s, node.Token, errors.ErrIndexMustBeInt, indexValue)
 	}
 
-	index := int(indexValue.(*values.NumericValue).Value)
-	// Convert 1-based to 0-based
-	index = index - 1
+	position := int(indexValue.(*values.NumericValue).Int)
 
-	// Handle string indexing
+	// Handle string indexing, by characters rather than bytes
 	if value.NodeType() == parser.NodeTypeString {
-		str := value.(*values.StringValue)
+		characters := []rune(value.(*values.StringValue).Value)
 
 		// Check bounds
-		if index < 0 || index >= len(str.Value) {
-			fmt.Printf("String index out of bounds: %d (string length: %d)\n", index+1, len(str.Value))
-			os.Exit(1)
-			return nil
+		index := zeroBasedIndex(position, len(characters))
+		if index < 0 || index >= len(characters) {
+			return nil, runtimeError(s, node.Token, errors.ErrStringIndexOutOfBounds, position, len(characters))
 		}
 
 		// Return single character as a string
 		return &values.StringValue{
 			Type:  parser.NodeTypeString,
-			Value: string(str.Value[index]),
-		}
+			Value: string(characters[index]),
+		}, nil
 	}
 
 	// Handle array indexing
@@ -287,171 +641,564 @@ func evaluateArrayIndex(node *parser.ArrayIndex, s *scope.Scope) values.RuntimeV
 		array := value.(*values.ArrayValue)
 
 		// Check bounds
+		index := zeroBasedIndex(position, len(array.Elements))
 		if index < 0 || index >= len(array.Elements) {
-			fmt.Printf("Array index out of bounds: %d (array length: %d)\n", index+1, len(array.Elements))
-			os.Exit(1)
-			return nil
+			return nil, runtimeError(s, node.Token, errors.ErrArrayIndexOutOfBounds, position, len(array.Elements))
 		}
 
-		return array.Elements[index]
+		return array.Elements[index], nil
 	}
 
 	// Not an array or string
-	fmt.Printf("Cannot index type: %s\n", value.NodeType())
-	os.Exit(1)
-	return nil
+	return nil, runtimeError(s, node.Token, errors.ErrCannotIndexType, value.NodeType())
+}
+
+// zeroBasedIndex converts a Gloob index to a Go one: positive indexes are 1-based
+// and negative ones count from the end, so -1 is the last element.
+// The result is out of range when the index is (0 included).
+func zeroBasedIndex(position int, length int) int {
+	if position < 0 {
+		return length + position
+	}
+	return position - 1
+}
+
+// evaluateSlice gives the elements of an array, or the characters of a string, at the indexes of a range.
+// Negative bounds count from the end and an open range goes up to the last element,
+// so arr[2..-2] drops the first and last elements and arr[2..] only the first one.
+// The direction comes from the resolved bounds, arr[-1..1] is the array reversed.
+func evaluateSlice(node *parser.ArrayIndex, value values.RuntimeValue, indexRange *values.RangeValue, s *scope.Scope) (values.RuntimeValue, error) {
+	var length int
+	var outOfBounds errors.Kind
+	var characters []rune
+	switch value := value.(type) {
+	case *values.ArrayValue:
+		length, outOfBounds = len(value.Elements), errors.ErrArrayIndexOutOfBounds
+	case *values.StringValue:
+		characters = []rune(value.Value)
+		length, outOfBounds = len(characters), errors.ErrStringIndexOutOfBounds
+	default:
+		return nil, runtimeError(s, node.Token, errors.ErrCannotIndexType, value.NodeType())
+	}
+
+	// Resolve the bounds against the length, 1-based like the indexes themselves
+	start := int64(zeroBasedIndex(int(indexRange.Start), length) + 1)
+	end := int64(length)
+	if !indexRange.Open {
+		end = int64(zeroBasedIndex(int(indexRange.End), length) + 1)
+	}
+	step := max(indexRange.Step, -indexRange.Step)
+	if !indexRange.Open && start > end {
+		step = -step
+	}
+	bounds := &values.RangeValue{Type: parser.NodeTypeRange, Start: start, End: end, Step: step}
+
+	var indexes []int
+	for i := int64(0); ; i++ {
+		position, ok := bounds.At(i)
+		if !ok {
+			break
+		}
+		if position < 1 || position > int64(length) {
+			return nil, runtimeError(s, node.Token, outOfBounds, position, length)
+		}
+		indexes = append(indexes, int(position-1))
+	}
+
+	if value.NodeType() == parser.NodeTypeString {
+		slice := make([]rune, len(indexes))
+		for i, index := range indexes {
+			slice[i] = characters[index]
+		}
+		return &values.StringValue{Type: parser.NodeTypeString, Value: string(slice)}, nil
+	}
+	array := value.(*values.ArrayValue)
+	elements := make([]values.RuntimeValue, len(indexes))
+	for i, index := range indexes {
+		elements[i] = array.Elements[index]
+	}
+	return &values.ArrayValue{Type: parser.NodeTypeArray, Elements: elements}, nil
+}
+
+// evaluateRangeExpression creates a range from its bounds and step, which must be ints.
+func evaluateRangeExpression(node *parser.RangeExpression, s *scope.Scope) (values.RuntimeValue, error) {
+	bound := func(expression parser.Expression) (int64, error) {
+		value, err := Evaluate(expression, s)
+		if err != nil {
+			return 0, err
+		}
+		number, ok := value.(*values.NumericValue)
+		if !ok || !number.IsInt {
+			return 0, runtimeError(s, node.Token, errors.ErrRangeNeedsInt, value)
+		}
+		return number.Int, nil
+	}
+
+	start, err := bound(node.Start)
+	if err != nil {
+		return nil, err
+	}
+	var end int64
+	if node.End != nil {
+		if end, err = bound(node.End); err != nil {
+			return nil, err
+		}
+	}
+	step := values.DefaultRangeStep(start, end, node.End == nil)
+	if node.Step != nil {
+		if step, err = bound(node.Step); err != nil {
+			return nil, err
+		}
+		if step == 0 {
+			return nil, runtimeError(s, node.Token, errors.ErrRangeStepZero)
+		}
+	}
+
+	return &values.RangeValue{
+		Type:  parser.NodeTypeRange,
+		Start: start,
+		End:   end,
+		Step:  step,
+		Open:  node.End == nil,
+	}, nil
 }
 
-func evaluateCallExpression(node *parser.CallExpression, s *scope.Scope) values.RuntimeValue {
+func evaluateCallExpression(node *parser.CallExpression, s *scope.Scope) (values.RuntimeValue, error) {
 	// Evaluate the callee (function identifier)
-	calleeValue := Evaluate(node.Callee, s)
+	calleeValue, err := Evaluate(node.Callee, s)
+	if err != nil {
+		return nil, err
+	}
+	if node.Optional && calleeValue.NodeType() == parser.NodeTypeNull {
+		return nil, optionalChainNull{}
+	}
+
+	// Evaluate all arguments
+	args, named, err := evaluateArguments(node.Args, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return callFunction(calleeValue, args, named, node.Token, s)
+}
 
+// callFunction calls a native or user-defined function with already evaluated arguments.
+// Errors without a position are reported at token, the call site.
+func callFunction(calleeValue values.RuntimeValue, args []values.RuntimeValue, named []namedArgument, token *lexer.Token, s *scope.Scope) (values.RuntimeValue, error) {
 	// Check if it's a native function
 	if calleeValue.NodeType() == parser.NodeTypeNativeFunction {
+		if len(named) > 0 {
+			return nil, runtimeError(s, named[0].token, errors.ErrNamedArgumentsNotSupported)
+		}
 
 		// Cast to NativeFunctionValue
 		nativeFunc, ok := calleeValue.(*values.NativeFunctionValue)
 		if !ok {
-			fmt.Printf("Invalid native function type\n")
-			os.Exit(1)
-			return nil
-		}
-
-		// Evaluate all arguments
-		args := make([]values.RuntimeValue, len(node.Args))
-		for i, arg := range node.Args {
-			args[i] = Evaluate(arg, s)
+			return nil, runtimeError(s, token, errors.ErrInvalidNativeFunction)
 		}
 
 		// Call the native function
-		return nativeFunc.Expression(args, s)
+		result, err := nativeFunc.Expression(args, s)
+		if err != nil {
+			return nil, locate(err, token, s)
+		}
+		return result, nil
 	}
 
 	if calleeValue.NodeType() == parser.NodeTypeFunctionDeclaration {
 		fun := calleeValue.(*values.FunctionValue)
 
-		// Check parameter count
-		if len(node.Args) != len(fun.Parameters) {
-			fmt.Printf("Function %s expects %d arguments, got %d\n", fun.Identifier, len(fun.Parameters), len(node.Args))
-			os.Exit(1)
-			return nil
-		}
-
-		// Evaluate all arguments
-		args := make([]values.RuntimeValue, len(node.Args))
-		for i, arg := range node.Args {
-			args[i] = Evaluate(arg, s)
+		// Deep recursion is stopped before it overflows the Go stack, so scripts can catch it
+		if !s.EnterCall(maxCallDepth) {
+			return nil, runtimeError(s, token, errors.ErrMaxCallDepth, maxCallDepth)
 		}
+		defer s.ExitCall()
 
-		// Create function scope
+		// Create function scope and declare the parameters in it
+		// The closure scope may belong to a scope shared by several sources, like the globals
+		// of an embedder running several scripts, so errors are shown with the function's own source
 		funScope := scope.NewScope(fun.Scope.(*scope.Scope))
-
-		// Declare parameters in function scope
-		for i, paramName := range fun.Parameters {
-			funScope.Declare(paramName, args[i], false)
+		funScope.SetSourceCode(fun.Source)
+		if err := bindParameters(fun, args, named, token, funScope, s); err != nil {
+			return nil, err
 		}
 
 		// Execute function body
-		var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
-		for _, statement := range fun.Body {
-			result = Evaluate(statement, funScope)
+		result, err := evaluateBlock(fun.Body, funScope)
+		if err != nil {
+			return nil, err
+		}
 
-			// Check if a return statement was executed
-			if result.NodeType() == parser.NodeTypeReturnValue {
-				// Unwrap and return the actual value
-				return result.(*values.ReturnValue).Value
-			}
+		// Check if a return statement was executed
+		if returnValue, ok := result.(*values.ReturnValue); ok {
+			// Unwrap and return the actual value
+			return returnValue.Value, nil
 		}
 
 		// Implicit return: return the last expression's value
-		return result
+		return result, nil
+	}
+
+	if class, ok := calleeValue.(*values.ClassValue); ok {
+		return instantiate(class, args, named, token, s)
+	}
+
+	return nil, runtimeError(s, token, errors.ErrCannotCallNonFunction, calleeValue.NodeType())
+}
+
+// bindParameters declares the parameters of fun in funScope.
+// Positional arguments are used first, then named arguments. Missing arguments take their
+// default value, evaluated in funScope so it can use the parameters before it.
+// A rest parameter gets an array with the remaining positional arguments.
+func bindParameters(fun *values.FunctionValue, args []values.RuntimeValue, named []namedArgument, token *lexer.Token, funScope *scope.Scope, s *scope.Scope) error {
+	names := []string{}
+	rest := false
+	for _, param := range fun.Parameters {
+		if param.Rest {
+			rest = true
+		} else {
+			names = append(names, param.Name)
+		}
+	}
+	if len(args) > len(names) && !rest {
+		return runtimeError(s, token, errors.ErrFunctionArgCountMismatch, fun.Identifier, arity(fun.Parameters), len(args))
+	}
+	byName, err := matchNamedArguments(fun.Identifier, names, len(args), named, s)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Cannot call non-function value: %s\n", calleeValue.NodeType())
-	os.Exit(1)
+	for i, param := range fun.Parameters {
+		var value values.RuntimeValue
+		switch namedValue, isNamed := byName[param.Name]; {
+		case param.Rest:
+			elements := []values.RuntimeValue{}
+			if i < len(args) {
+				elements = append(elements, args[i:]...)
+			}
+			value = &values.ArrayValue{Type: parser.NodeTypeArray, Elements: elements}
+		case i < len(args):
+			value = args[i]
+		case isNamed:
+			value = namedValue
+		case param.Default != nil:
+			if value, err = Evaluate(param.Default, funScope); err != nil {
+				return err
+			}
+		default:
+			return runtimeError(s, token, errors.ErrMissingArgument, fun.Identifier, param.Name)
+		}
+		if _, err := funScope.Declare(param.Name, value, false); err != nil {
+			return locate(err, token, s)
+		}
+	}
 	return nil
 }
 
-func evaluateFunctionDeclaration(node *parser.FunctionDeclaration, s *scope.Scope) values.RuntimeValue {
+// matchNamedArguments maps named arguments to the names they refer to.
+// names are the names that can be given, the first positional of them already got a positional argument.
+func matchNamedArguments(callee string, names []string, positional int, named []namedArgument, s *scope.Scope) (map[string]values.RuntimeValue, error) {
+	byName := make(map[string]values.RuntimeValue, len(named))
+	for _, arg := range named {
+		index := slices.Index(names, arg.name)
+		if index == -1 {
+			return nil, runtimeError(s, arg.token, errors.ErrUnknownNamedArgument, callee, arg.name)
+		}
+		if _, given := byName[arg.name]; given || index < positional {
+			return nil, runtimeError(s, arg.token, errors.ErrDuplicateArgument, arg.name, callee)
+		}
+		byName[arg.name] = arg.value
+	}
+	return byName, nil
+}
+
+// arity describes how many arguments a function takes, for error messages.
+// Examples: "1 argument", "2 arguments", "1 to 3 arguments", "at least 1 argument"
+func arity(params []parser.Parameter) string {
+	required, total, rest := 0, 0, false
+	for _, param := range params {
+		switch {
+		case param.Rest:
+			rest = true
+		case param.Default == nil:
+			required++
+			total++
+		default:
+			total++
+		}
+	}
+
+	arguments := func(count int) string {
+		if count == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", count)
+	}
+	switch {
+	case rest:
+		return "at least " + arguments(required)
+	case required == total:
+		return arguments(total)
+	default:
+		return fmt.Sprintf("%d to %s", required, arguments(total))
+	}
+}
+
+// namedArgument is an evaluated argument passed by name: connect(host: "localhost")
+type namedArgument struct {
+	name  string
+	value values.RuntimeValue
+	token *lexer.Token
+}
+
+// evaluateArguments evaluates the arguments of a call from left to right.
+// Spread arrays are expanded into positional arguments, named arguments are returned apart.
+func evaluateArguments(nodes []parser.Expression, s *scope.Scope) ([]values.RuntimeValue, []namedArgument, error) {
+	args := make([]values.RuntimeValue, 0, len(nodes))
+	var named []namedArgument
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *parser.SpreadElement:
+			value, err := Evaluate(node.Argument, s)
+			if err != nil {
+				return nil, nil, err
+			}
+			array, ok := value.(*values.ArrayValue)
+			if !ok {
+				return nil, nil, runtimeError(s, node.Token, errors.ErrSpreadNeedsArray, value.NodeType())
+			}
+			args = append(args, array.Elements...)
+		case *parser.NamedArgument:
+			value, err := Evaluate(node.Value, s)
+			if err != nil {
+				return nil, nil, err
+			}
+			named = append(named, namedArgument{name: node.Name, value: value, token: node.Token})
+		default:
+			value, err := Evaluate(node, s)
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, value)
+		}
+	}
+	return args, named, nil
+}
+
+func evaluateFunctionDeclaration(node *parser.FunctionDeclaration, s *scope.Scope) (values.RuntimeValue, error) {
 	fun := &values.FunctionValue{
 		Type:       parser.NodeTypeFunctionDeclaration,
 		Identifier: node.Identifier,
 		Parameters: node.Parameters,
 		Body:       node.Body,
 		Scope:      s,
+		Source:     s.SourceCode(),
+	}
+	if _, err := s.Declare(node.Identifier, fun, false); err != nil {
+		return nil, locate(err, node.Token, s)
+	}
+	return fun, nil
+}
+
+func evaluateClassDeclaration(node *parser.ClassDeclaration, s *scope.Scope) (values.RuntimeValue, error) {
+	class := &values.ClassValue{
+		Type:    parser.NodeTypeClass,
+		Name:    node.Name,
+		Fields:  node.Fields,
+		Methods: make(map[string]*values.FunctionValue, len(node.Methods)),
+		Scope:   s,
+		Source:  s.SourceCode(),
+	}
+	for _, method := range node.Methods {
+		class.Methods[method.Identifier] = &values.FunctionValue{
+			Type:       parser.NodeTypeFunctionDeclaration,
+			Identifier: method.Identifier,
+			Parameters: method.Parameters,
+			Body:       method.Body,
+			Scope:      s,
+			Source:     s.SourceCode(),
+		}
+	}
+	if _, err := s.Declare(node.Name, class, false); err != nil {
+		return nil, locate(err, node.Token, s)
+	}
+	return class, nil
+}
+
+// instantiate creates an instance of class. Fields start with their default value (null if they have none).
+// With an init method the arguments are passed to it, otherwise they fill the fields in order
+// and named arguments set the fields with their name.
+func instantiate(class *values.ClassValue, args []values.RuntimeValue, named []namedArgument, token *lexer.Token, s *scope.Scope) (values.RuntimeValue, error) {
+	instance := values.NewObjectValue()
+	instance.Class = class
+	classScope := scope.NewScope(class.Scope.(*scope.Scope))
+	classScope.SetSourceCode(class.Source)
+	for _, field := range class.Fields {
+		// Defaults are evaluated for every instance, so a field = [] is never shared
+		var value values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
+		if field.Default != nil {
+			var err error
+			value, err = Evaluate(field.Default, classScope)
+			if err != nil {
+				return nil, err
+			}
+		}
+		instance.Set(field.Name, value)
 	}
-	s.Declare(node.Identifier, fun, false)
-	return fun
+
+	if init, ok := class.Methods["init"]; ok {
+		if _, err := callFunction(bindMethod(init, instance), args, named, token, s); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	}
+
+	if len(args) > len(class.Fields) {
+		return nil, runtimeError(s, token, errors.ErrTooManyConstructorArgs, class.Name, len(class.Fields), len(args))
+	}
+	for i, arg := range args {
+		instance.Set(class.Fields[i].Name, arg)
+	}
+
+	fields := make([]string, len(class.Fields))
+	for i, field := range class.Fields {
+		fields[i] = field.Name
+	}
+	byName, err := matchNamedArguments(class.Name, fields, len(args), named, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range named {
+		instance.Set(arg.name, byName[arg.name])
+	}
+	return instance, nil
+}
+
+// bindMethod returns a copy of method that sees instance as self.
+func bindMethod(method *values.FunctionValue, instance *values.ObjectValue) *values.FunctionValue {
+	methodScope := scope.NewScope(method.Scope.(*scope.Scope))
+	methodScope.Declare("self", instance, true) // can't fail, the scope is new
+	bound := *method
+	bound.Scope = methodScope
+	return &bound
+}
+
+// evaluateIsExpression checks if a value is an instance of a class: p is Point
+func evaluateIsExpression(node *parser.BinaryExpression, left values.RuntimeValue, right values.RuntimeValue, s *scope.Scope) (values.RuntimeValue, error) {
+	class, ok := right.(*values.ClassValue)
+	if !ok {
+		return nil, runtimeError(s, node.Token, errors.ErrIsNeedsClass, right.NodeType())
+	}
+	object, ok := left.(*values.ObjectValue)
+	return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: ok && object.Class == class}, nil
+}
+
+// maxCallDepth is how many calls to Gloob functions can be running at the same time.
+const maxCallDepth = 5000
+
+// anonymousFunctionName is the name given to functions created by function expressions.
+const anonymousFunctionName = "<anonymous>"
+
+// evaluateFunctionExpression creates a function value capturing the current scope, without declaring it.
+func evaluateFunctionExpression(node *parser.FunctionExpression, s *scope.Scope) (values.RuntimeValue, error) {
+	return &values.FunctionValue{
+		Type:       parser.NodeTypeFunctionDeclaration,
+		Identifier: anonymousFunctionName,
+		Parameters: node.Parameters,
+		Body:       node.Body,
+		Scope:      s,
+		Source:     s.SourceCode(),
+	}, nil
 }
 
 // Helper function to check if an operator is a comparison operator
 func isComparisonOperator(operator string) bool {
 	switch operator {
-	case "==", "!=", ">", ">=", "<", "<=", "&&", "||":
+	case "==", "!=", ">", ">=", "<", "<=":
 		return true
 	default:
 		return false
 	}
 }
 
-// Evaluate comparison expressions
-func evaluateComparisonExpression(operator string, left values.RuntimeValue, right values.RuntimeValue, s *scope.Scope) values.RuntimeValue {
-	// Handle logical operators first (they have special behavior)
-	if operator == "&&" || operator == "||" {
-		return evaluateLogicalExpression(operator, left, right, s)
+// Helper function to check if an operator is a logical operator
+func isLogicalOperator(operator string) bool {
+	switch operator {
+	case "&&", "||", "??":
+		return true
+	default:
+		return false
 	}
+}
+
+// Evaluate comparison expressions
+func evaluateComparisonExpression(node *parser.BinaryExpression, left values.RuntimeValue, right values.RuntimeValue, s *scope.Scope) (values.RuntimeValue, error) {
+	operator := node.Operator
 
+	var result values.RuntimeValue
+
+	switch {
 	// Handle string comparisons
-	if left.NodeType() == parser.NodeTypeString && right.NodeType() == parser.NodeTypeString {
-		return evaluateStringComparison(operator, left.(*values.StringValue), right.(*values.StringValue))
-	}
+	case left.NodeType() == parser.NodeTypeString && right.NodeType() == parser.NodeTypeString:
+		result = evaluateStringComparison(operator, left.(*values.StringValue), right.(*values.StringValue))
 
 	// Handle numeric comparisons
-	if left.NodeType() == parser.NodeTypeNumeric && right.NodeType() == parser.NodeTypeNumeric {
-		return evaluateNumericComparison(operator, left.(*values.NumericValue), right.(*values.NumericValue))
-	}
+	case left.NodeType() == parser.NodeTypeNumeric && right.NodeType() == parser.NodeTypeNumeric:
+		result = evaluateNumericComparison(operator, left.(*values.NumericValue), right.(*values.NumericValue))
 
 	// Handle boolean comparisons
-	if left.NodeType() == parser.NodeTypeBoolean && right.NodeType() == parser.NodeTypeBoolean {
-		return evaluateBooleanComparison(operator, left.(*values.BooleanValue), right.(*values.BooleanValue))
-	}
+	case left.NodeType() == parser.NodeTypeBoolean && right.NodeType() == parser.NodeTypeBoolean:
+		result = evaluateBooleanComparison(operator, left.(*values.BooleanValue), right.(*values.BooleanValue))
 
 	// Handle null comparisons
-	if left.NodeType() == parser.NodeTypeNull && right.NodeType() == parser.NodeTypeNull {
-		return evaluateNullComparison(operator)
-	}
+	case left.NodeType() == parser.NodeTypeNull && right.NodeType() == parser.NodeTypeNull:
+		result = evaluateNullComparison(operator)
+		if result == nil {
+			return nil, runtimeError(s, node.Token, errors.ErrCannotUseOperatorWithNull, operator)
+		}
+		return result, nil
 
 	// Mixed type comparisons (only == and != are allowed)
-	if operator == "==" {
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: false}
-	}
-	if operator == "!=" {
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: true}
+	case operator == "==":
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: false}, nil
+	case operator == "!=":
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: true}, nil
+
+	default:
+		return nil, runtimeError(s, node.Token, errors.ErrCannotCompareTypes, left.NodeType(), right.NodeType(), operator)
 	}
 
-	fmt.Printf("Cannot compare %s and %s with operator %s\n", left.NodeType(), right.NodeType(), operator)
-	os.Exit(1)
-	return nil
+	if result == nil {
+		return nil, runtimeError(s, node.Token, errors.ErrUnknownComparisonOperator, operator)
+	}
+	return result, nil
 }
 
-// evaluateLogicalExpression handles logical operators && and ||
-func evaluateLogicalExpression(operator string, left values.RuntimeValue, right values.RuntimeValue, s *scope.Scope) values.RuntimeValue {
-	// Coerce both operands to boolean values
-	leftBool := isTruthy(left)
-	rightBool := isTruthy(right)
-
-	switch operator {
+// evaluateLogicalExpression handles the logical operators &&, || and ??.
+// They short-circuit and return the operand that decides the result, not a boolean:
+// - a && b returns a when it is falsy, otherwise b
+// - a || b returns a when it is truthy, otherwise b (name || "default")
+// - a ?? b returns a unless it is null, otherwise b
+func evaluateLogicalExpression(node *parser.BinaryExpression, left values.RuntimeValue, s *scope.Scope) (values.RuntimeValue, error) {
+	var decided bool
+	switch node.Operator {
 	case "&&":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: leftBool && rightBool}
+		decided = !values.IsTruthy(left)
 	case "||":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: leftBool || rightBool}
+		decided = values.IsTruthy(left)
+	case "??":
+		decided = left.NodeType() != parser.NodeTypeNull
 	default:
-		fmt.Printf("Unknown logical operator: %s\n", operator)
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrUnknownLogicalOperator, node.Operator)
+	}
+
+	if decided {
+		return left, nil
 	}
+	return Evaluate(node.Right, s)
 }
 
+// evaluateStringComparison compares two strings. It returns nil for unknown operators.
 func evaluateStringComparison(operator string, left *values.StringValue, right *values.StringValue) values.RuntimeValue {
 	switch operator {
 	case "==":
@@ -467,33 +1214,40 @@ func evaluateStringComparison(operator string, left *values.StringValue, right *
 	case "<=":
 		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value <= right.Value}
 	default:
-		fmt.Printf("Unknown string comparison operator: %s\n", operator)
-		os.Exit(1)
 		return nil
 	}
 }
 
+// evaluateNumericComparison compares two numbers. It returns nil for unknown operators.
+// Two ints are compared exactly, so 1 == 1.0 is true but huge ints don't lose precision.
 func evaluateNumericComparison(operator string, left *values.NumericValue, right *values.NumericValue) values.RuntimeValue {
+	if left.IsInt && right.IsInt {
+		return compareOrdered(operator, left.Int, right.Int)
+	}
+	return compareOrdered(operator, left.Value, right.Value)
+}
+
+// compareOrdered applies a comparison operator to two ordered values. It returns nil for unknown operators.
+func compareOrdered[T cmp.Ordered](operator string, left T, right T) values.RuntimeValue {
 	switch operator {
 	case "==":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value == right.Value}
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left == right}
 	case "!=":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value != right.Value}
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left != right}
 	case ">":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value > right.Value}
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left > right}
 	case ">=":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value >= right.Value}
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left >= right}
 	case "<":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value < right.Value}
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left < right}
 	case "<=":
-		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value <= right.Value}
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left <= right}
 	default:
-		fmt.Printf("Unknown numeric comparison operator: %s\n", operator)
-		os.Exit(1)
 		return nil
 	}
 }
 
+// evaluateBooleanComparison compares two booleans. It returns nil for unknown operators.
 func evaluateBooleanComparison(operator string, left *values.BooleanValue, right *values.BooleanValue) values.RuntimeValue {
 	switch operator {
 	case "==":
@@ -514,12 +1268,11 @@ func evaluateBooleanComparison(operator string, left *values.BooleanValue, right
 		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: left.Value || right.Value}
 
 	default:
-		fmt.Printf("Unknown boolean comparison operator: %s\n", operator)
-		os.Exit(1)
 		return nil
 	}
 }
 
+// evaluateNullComparison compares two nulls. It returns nil for operators other than == and !=.
 func evaluateNullComparison(operator string) values.RuntimeValue {
 	switch operator {
 	case "==":
@@ -527,69 +1280,193 @@ func evaluateNullComparison(operator string) values.RuntimeValue {
 	case "!=":
 		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: false}
 	default:
-		fmt.Printf("Cannot use operator %s with null values\n", operator)
-		os.Exit(1)
 		return nil
 	}
 }
 
-func evaluateIfStatement(node *parser.IfStatement, s *scope.Scope) values.RuntimeValue {
+func evaluateIfStatement(node *parser.IfStatement, s *scope.Scope) (values.RuntimeValue, error) {
 	// Evaluate the condition
-	conditionValue := Evaluate(node.Condition, s)
+	conditionValue, err := Evaluate(node.Condition, s)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check if condition is truthy
-	if isTruthy(conditionValue) {
+	if values.IsTruthy(conditionValue) {
 		// Execute if body
-		var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
-		for _, statement := range node.Body {
-			result = Evaluate(statement, s)
-		}
-		return result
+		return evaluateBlock(node.Body, scope.NewScope(s))
 	}
 
 	// Check elseif clauses
 	for _, elseifClause := range node.ElseIfs {
-		elseifValue := Evaluate(elseifClause.Condition, s)
-		if isTruthy(elseifValue) {
-			var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
-			for _, statement := range elseifClause.Body {
-				result = Evaluate(statement, s)
-			}
-			return result
+		elseifValue, err := Evaluate(elseifClause.Condition, s)
+		if err != nil {
+			return nil, err
+		}
+		if values.IsTruthy(elseifValue) {
+			return evaluateBlock(elseifClause.Body, scope.NewScope(s))
 		}
 	}
 
 	// Execute else body if it exists
 	if len(node.ElseBody) > 0 {
-		var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
-		for _, statement := range node.ElseBody {
-			result = Evaluate(statement, s)
-		}
-		return result
+		return evaluateBlock(node.ElseBody, scope.NewScope(s))
 	}
 
 	// Return null if no condition was met and no else clause
-	return &values.NullValue{Type: parser.NodeTypeNull}
+	return &values.NullValue{Type: parser.NodeTypeNull}, nil
 }
 
-// Helper function to determine if a value is truthy
-func isTruthy(value values.RuntimeValue) bool {
-	switch v := value.(type) {
-	case *values.BooleanValue:
-		return v.Value
-	case *values.NumericValue:
-		return v.Value != 0
-	case *values.StringValue:
-		return v.Value != ""
-	case *values.NullValue:
-		return false
+// evaluateMatchExpression evaluates the body of the first arm whose pattern matches the value.
+// Every attempt gets its own scope, so the names bound by a pattern only exist in its guard and body.
+func evaluateMatchExpression(node *parser.MatchExpression, s *scope.Scope) (values.RuntimeValue, error) {
+	value, err := Evaluate(node.Value, s)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range node.Arms {
+		for _, pattern := range arm.Patterns {
+			var bindings []binding
+			matched, err := matchPattern(pattern, value, &bindings, s)
+			if err != nil {
+				return nil, err
+			}
+			armScope := scope.NewScope(s)
+			if matched {
+				if err := declareBindings(bindings, armScope); err != nil {
+					return nil, err
+				}
+			}
+			if matched && arm.Guard != nil {
+				guardValue, err := Evaluate(arm.Guard, armScope)
+				if err != nil {
+					return nil, err
+				}
+				matched = values.IsTruthy(guardValue)
+			}
+			if matched {
+				return evaluateBlock(arm.Body, armScope)
+			}
+		}
+	}
+
+	return nil, runtimeError(s, node.Token, errors.ErrNoMatchingPattern, value)
+}
+
+// binding is a name bound by a pattern to the part of the value it matched.
+type binding struct {
+	name  string
+	value values.RuntimeValue
+	token *lexer.Token
+}
+
+// declareBindings declares the names bound by a pattern as variables of s.
+func declareBindings(bindings []binding, s *scope.Scope) error {
+	for _, binding := range bindings {
+		if _, err := s.Declare(binding.name, binding.value, false); err != nil {
+			return locate(err, binding.token, s)
+		}
+	}
+	return nil
+}
+
+// destructure binds the names of a declaration, assignment or loop pattern,
+// failing if the value doesn't have the shape of the pattern.
+func destructure(pattern parser.Expression, value values.RuntimeValue, token *lexer.Token, s *scope.Scope) ([]binding, error) {
+	var bindings []binding
+	matched, err := matchPattern(pattern, value, &bindings, s)
+	if err != nil {
+		return nil, err
+	}
+	if !matched {
+		return nil, runtimeError(s, token, errors.ErrDestructuringMismatch, value)
+	}
+	return bindings, nil
+}
+
+// matchPattern checks if value matches pattern, collecting the names bound by the pattern in bindings.
+// Arrays match arrays of the same length (at least as long when the pattern ends with ...rest),
+// objects match objects having at least the pattern keys.
+func matchPattern(pattern parser.Expression, value values.RuntimeValue, bindings *[]binding, s *scope.Scope) (bool, error) {
+	switch pattern := pattern.(type) {
+	case *parser.Identifier:
+		if pattern.Name != "_" {
+			*bindings = append(*bindings, binding{name: pattern.Name, value: value, token: pattern.Token})
+		}
+		return true, nil
+	case *parser.Array:
+		array, ok := value.(*values.ArrayValue)
+		if !ok {
+			return false, nil
+		}
+		elements := pattern.Elements
+		var rest *parser.SpreadElement
+		if len(elements) > 0 {
+			if spread, ok := elements[len(elements)-1].(*parser.SpreadElement); ok {
+				rest = spread
+				elements = elements[:len(elements)-1]
+			}
+		}
+		if len(array.Elements) < len(elements) || (rest == nil && len(array.Elements) != len(elements)) {
+			return false, nil
+		}
+		for i, element := range elements {
+			if matched, err := matchPattern(element, array.Elements[i], bindings, s); err != nil || !matched {
+				return false, err
+			}
+		}
+		if rest != nil {
+			remaining := append([]values.RuntimeValue{}, array.Elements[len(elements):]...)
+			restValue := &values.ArrayValue{Type: parser.NodeTypeArray, Elements: remaining}
+			return matchPattern(rest.Argument, restValue, bindings, s)
+		}
+		return true, nil
+	case *parser.Object:
+		object, ok := value.(*values.ObjectValue)
+		if !ok {
+			return false, nil
+		}
+		for _, property := range pattern.Properties {
+			propertyValue, exists := object.Get(property.Key)
+			if !exists {
+				return false, nil
+			}
+			if matched, err := matchPattern(property.Value, propertyValue, bindings, s); err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
 	default:
-		return true // Objects, functions, etc. are truthy
+		// Literals match the values equal to them
+		literal, err := Evaluate(pattern, s)
+		if err != nil {
+			return false, err
+		}
+		return values.Equal(literal, value), nil
+	}
+}
+
+// evaluateBlock executes the statements of a block and returns the last result.
+// A break, continue or return stops the block right away so the enclosing construct can handle it.
+func evaluateBlock(body []parser.Statement, s *scope.Scope) (values.RuntimeValue, error) {
+	var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
+	for _, statement := range body {
+		value, err := Evaluate(statement, s)
+		if err != nil {
+			return nil, err
+		}
+		result = value
+		if isControlFlow(result) {
+			break
+		}
 	}
+	return result, nil
 }
 
-func evaluateLoopStatement(node *parser.LoopStatement, s *scope.Scope) values.RuntimeValue {
+func evaluateLoopStatement(node *parser.LoopStatement, s *scope.Scope) (values.RuntimeValue, error) {
 	var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
+	var err error
 
 	// Check if this is a for-each loop
 	if node.IsForEach {
@@ -605,75 +1482,83 @@ func evaluateLoopStatement(node *parser.LoopStatement, s *scope.Scope) values.Ru
 	if node.Condition == nil {
 		// Infinite loop - treat condition as always true
 		for {
-			// Execute loop body
-			for _, statement := range node.Body {
-				result = Evaluate(statement, s)
-				// Check if break was executed
-				if result.NodeType() == parser.NodeTypeBreakExpression {
-					return &values.NullValue{Type: parser.NodeTypeNull}
-				}
+			// Execute loop body, every iteration gets its own scope
+			result, err = evaluateBlock(node.Body, scope.NewScope(s))
+			if err != nil {
+				return nil, err
+			}
+			// Check if break, continue or return was executed
+			if exit, value := loopControl(result, node.Label); exit {
+				return value, nil
 			}
 		}
 	}
 
 	// Loop with condition
 	// Evaluate the condition
-	conditionValue := Evaluate(node.Condition, s)
+	conditionValue, err := Evaluate(node.Condition, s)
+	if err != nil {
+		return nil, err
+	}
 
 	// Continue looping while the condition is truthy
-	for isTruthy(conditionValue) {
-		// Execute loop body
-		for _, statement := range node.Body {
-			result = Evaluate(statement, s)
-			// Check if break was executed
-			if result.NodeType() == parser.NodeTypeBreakExpression {
-				return &values.NullValue{Type: parser.NodeTypeNull}
-			}
+	for values.IsTruthy(conditionValue) {
+		// Execute loop body, every iteration gets its own scope
+		result, err = evaluateBlock(node.Body, scope.NewScope(s))
+		if err != nil {
+			return nil, err
+		}
+		// Check if break, continue or return was executed
+		var exit bool
+		if exit, result = loopControl(result, node.Label); exit {
+			return result, nil
 		}
 
 		// Re-evaluate the condition to check if we should continue
-		conditionValue = Evaluate(node.Condition, s)
+		conditionValue, err = Evaluate(node.Condition, s)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return result
+	return result, nil
 }
 
 // evaluateRangeLoop executes a range-based loop (loop i from X to Y)
-func evaluateRangeLoop(node *parser.LoopStatement, s *scope.Scope) values.RuntimeValue {
-	fromValue := Evaluate(node.From, s)
-	toValue := Evaluate(node.To, s)
+func evaluateRangeLoop(node *parser.LoopStatement, s *scope.Scope) (values.RuntimeValue, error) {
+	fromValue, err := Evaluate(node.From, s)
+	if err != nil {
+		return nil, err
+	}
+	toValue, err := Evaluate(node.To, s)
+	if err != nil {
+		return nil, err
+	}
 
 	// Validate types
 	if fromValue.NodeType() != parser.NodeTypeNumeric || toValue.NodeType() != parser.NodeTypeNumeric {
-		fmt.Printf("Range loop requires numeric values for 'from' and 'to'\n")
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, node.Token, errors.ErrRangeLoopNeedsNumeric)
 	}
 
 	fromNumeric := fromValue.(*values.NumericValue)
 	toNumeric := toValue.(*values.NumericValue)
 
 	// Determine increment (default is 1)
+	// The loop variable is an int as long as the start and the increment are ints
 	increment := 1.0
+	var intIncrement int64 = 1
+	integers := fromNumeric.IsInt
 	if node.Increment != nil {
-		incValue := Evaluate(node.Increment, s)
+		incValue, err := Evaluate(node.Increment, s)
+		if err != nil {
+			return nil, err
+		}
 		if incValue.NodeType() != parser.NodeTypeNumeric {
-			fmt.Printf("Range loop increment must be numeric\n")
-			os.Exit(1)
-			return nil
+			return nil, runtimeError(s, node.Token, errors.ErrRangeLoopIncrementNumeric)
 		}
 		increment = incValue.(*values.NumericValue).Value
-	}
-
-	// Check if loop variable already exists, if not declare it
-	// We need to manually check since Declare will exit if variable exists
-	scopeVars := s.GetVariables()
-	_, exists := scopeVars[node.LoopVar]
-	if !exists {
-		scopeVars[node.LoopVar] = &values.NumericValue{Type: parser.NodeTypeNumeric, Value: fromNumeric.Value}
-	} else {
-		// Variable exists, just update its value
-		scopeVars[node.LoopVar] = &values.NumericValue{Type: parser.NodeTypeNumeric, Value: fromNumeric.Value}
+		intIncrement = incValue.(*values.NumericValue).Int
+		integers = integers && incValue.(*values.NumericValue).IsInt
 	}
 
 	var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
@@ -683,98 +1568,296 @@ func evaluateRangeLoop(node *parser.LoopStatement, s *scope.Scope) values.Runtim
 	goingForward := true
 	if node.Increment != nil {
 		goingForward = increment > 0
-	} else {
-		goingForward = fromNumeric.Value <= toNumeric.Value
+	} else if fromNumeric.IsInt && toNumeric.IsInt && fromNumeric.Int > toNumeric.Int ||
+		!(fromNumeric.IsInt && toNumeric.IsInt) && fromNumeric.Value > toNumeric.Value {
+		// Without an increment, a loop from a bigger number counts down
+		goingForward = false
+		increment, intIncrement = -1, -1
 	}
 
+	// Ints are counted exactly in an int64, floats lose precision past 2^53 and would get stuck
 	current := fromNumeric.Value
+	intCurrent := fromNumeric.Int
+	pastEnd := func() bool {
+		if integers && toNumeric.IsInt {
+			return goingForward && intCurrent > toNumeric.Int || !goingForward && intCurrent < toNumeric.Int
+		}
+		if integers {
+			current = float64(intCurrent)
+		}
+		return goingForward && current > toNumeric.Value || !goingForward && current < toNumeric.Value
+	}
 
 	// Execute loop
-	for {
-		// Check termination based on direction
-		if goingForward && current > toNumeric.Value {
-			break
+	for !pastEnd() {
+		// Every iteration gets its own scope holding the loop variable,
+		// so closures created in the body capture the value of that iteration
+		iterationScope := scope.NewScope(s)
+		loopValue := values.NewFloat(current)
+		if integers {
+			loopValue = values.NewInt(intCurrent)
 		}
-		if !goingForward && current < toNumeric.Value {
-			break
+		if _, err := iterationScope.Declare(node.LoopVar, loopValue, false); err != nil {
+			return nil, locate(err, node.Token, s)
 		}
 
-		// Update loop variable value
-		s.Assign(node.LoopVar, &values.NumericValue{Type: parser.NodeTypeNumeric, Value: current})
-
 		// Execute loop body
-		for _, statement := range node.Body {
-			result = Evaluate(statement, s)
-			// Check if break was executed
-			if result.NodeType() == parser.NodeTypeBreakExpression {
-				return &values.NullValue{Type: parser.NodeTypeNull}
-			}
+		result, err = evaluateBlock(node.Body, iterationScope)
+		if err != nil {
+			return nil, err
+		}
+		// Check if break, continue or return was executed
+		var exit bool
+		if exit, result = loopControl(result, node.Label); exit {
+			return result, nil
 		}
 
-		current += increment
+		if !integers {
+			current += increment
+			continue
+		}
+		// A next value that doesn't fit in an int is past any end, so the loop is over
+		next := intCurrent + intIncrement
+		if (next > intCurrent) != (intIncrement > 0) {
+			break
+		}
+		intCurrent = next
 	}
 
-	return result
+	return result, nil
 }
 
 // evaluateForEachLoop executes a for-each loop (loop element from arr { })
-func evaluateForEachLoop(node *parser.LoopStatement, s *scope.Scope) values.RuntimeValue {
-	// Evaluate the iterable (should be an array)
-	iterableValue := Evaluate(node.From, s)
-
-	// Validate that it's an array
-	if iterableValue.NodeType() != parser.NodeTypeArray {
-		fmt.Printf("For-each loop requires an array, got %s\n", iterableValue.NodeType())
-		os.Exit(1)
-		return nil
+func evaluateForEachLoop(node *parser.LoopStatement, s *scope.Scope) (values.RuntimeValue, error) {
+	// Evaluate the iterable (should be an array or an object)
+	iterableValue, err := Evaluate(node.From, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// entry gives the key and value of the i-th iteration: 1-based indexes and elements for arrays,
+	// keys in insertion order and their values for objects, and 1-based positions and numbers for ranges.
+	// Arrays and objects are collected up front, ranges are iterated lazily so open ranges work.
+	var entry func(i int) (key values.RuntimeValue, item values.RuntimeValue, ok bool)
+	switch iterable := iterableValue.(type) {
+	case *values.ArrayValue, *values.ObjectValue:
+		var keys, items []values.RuntimeValue
+		if array, ok := iterable.(*values.ArrayValue); ok {
+			for i, element := range array.Elements {
+				keys = append(keys, values.NewInt(int64(i+1)))
+				items = append(items, element)
+			}
+		} else {
+			object := iterable.(*values.ObjectValue)
+			for _, key := range object.Keys() {
+				keys = append(keys, &values.StringValue{Type: parser.NodeTypeString, Value: key})
+				items = append(items, object.Properties[key])
+			}
+		}
+		entry = func(i int) (values.RuntimeValue, values.RuntimeValue, bool) {
+			if i >= len(keys) {
+				return nil, nil, false
+			}
+			return keys[i], items[i], true
+		}
+	case *values.RangeValue:
+		entry = func(i int) (values.RuntimeValue, values.RuntimeValue, bool) {
+			number, ok := iterable.At(int64(i))
+			return values.NewInt(int64(i + 1)), values.NewInt(number), ok
+		}
+	default:
+		return nil, runtimeError(s, node.Token, errors.ErrForEachNeedsArray, iterableValue.NodeType())
 	}
 
-	arrayValue := iterableValue.(*values.ArrayValue)
 	var result values.RuntimeValue = &values.NullValue{Type: parser.NodeTypeNull}
 
-	// Iterate over each element in the array
-	for _, element := range arrayValue.Elements {
-		// Check if loop variable already exists, if not declare it
-		scopeVars := s.GetVariables()
-		_, exists := scopeVars[node.LoopVar]
-		if !exists {
-			scopeVars[node.LoopVar] = element
+	for i := 0; ; i++ {
+		key, item, ok := entry(i)
+		if !ok {
+			break
+		}
+
+		// Every iteration gets its own scope holding the loop variables
+		iterationScope := scope.NewScope(s)
+		if node.Pattern != nil {
+			// The pattern destructures every element of arrays and every [key, value] pair of objects
+			if iterableValue.NodeType() == parser.NodeTypeObject {
+				item = &values.ArrayValue{Type: parser.NodeTypeArray, Elements: []values.RuntimeValue{key, item}}
+			}
+			bindings, err := destructure(node.Pattern, item, node.Token, s)
+			if err != nil {
+				return nil, err
+			}
+			if err := declareBindings(bindings, iterationScope); err != nil {
+				return nil, err
+			}
+		} else if node.ValueVar != "" {
+			if _, err := iterationScope.Declare(node.LoopVar, key, false); err != nil {
+				return nil, locate(err, node.Token, s)
+			}
+			if _, err := iterationScope.Declare(node.ValueVar, item, false); err != nil {
+				return nil, locate(err, node.Token, s)
+			}
 		} else {
-			// Variable exists, just update its value
-			scopeVars[node.LoopVar] = element
+			// With a single variable arrays give their elements and objects their keys
+			variable := item
+			if iterableValue.NodeType() == parser.NodeTypeObject {
+				variable = key
+			}
+			if _, err := iterationScope.Declare(node.LoopVar, variable, false); err != nil {
+				return nil, locate(err, node.Token, s)
+			}
 		}
 
 		// Execute loop body
-		for _, statement := range node.Body {
-			result = Evaluate(statement, s)
-			// Check if break was executed
-			if result.NodeType() == parser.NodeTypeBreakExpression {
-				return &values.NullValue{Type: parser.NodeTypeNull}
-			}
+		result, err = evaluateBlock(node.Body, iterationScope)
+		if err != nil {
+			return nil, err
+		}
+		// Check if break, continue or return was executed
+		var exit bool
+		if exit, result = loopControl(result, node.Label); exit {
+			return result, nil
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+func evaluateBreakExpression(node *parser.BreakExpression, _ *scope.Scope) (values.RuntimeValue, error) {
+	return &values.BreakValue{Type: parser.NodeTypeBreakExpression, Label: node.Label}, nil
+}
+
+func evaluateContinueExpression(node *parser.ContinueExpression, _ *scope.Scope) (values.RuntimeValue, error) {
+	return &values.ContinueValue{Type: parser.NodeTypeContinueExpression, Label: node.Label}, nil
+}
+
+// isControlFlow reports whether value is a break, continue or return signal.
+// Every block stops as soon as one of its statements produces a signal and hands it
+// to the enclosing construct: loops consume break/continue, functions consume return.
+func isControlFlow(value values.RuntimeValue) bool {
+	switch value.NodeType() {
+	case parser.NodeTypeBreakExpression, parser.NodeTypeContinueExpression, parser.NodeTypeReturnValue:
+		return true
+	}
+	return false
 }
 
-func evaluateBreakExpression(_ *parser.BreakExpression, _ *scope.Scope) values.RuntimeValue {
-	return &values.BreakValue{Type: parser.NodeTypeBreakExpression}
+// loopControl decides what a loop does with the result of one run of its body.
+// It returns exit=true when the loop must stop, together with the value the loop evaluates to:
+// null for a break aimed at this loop, or the signal itself when it belongs to an
+// enclosing construct (a return, or a labeled break/continue for an outer loop).
+func loopControl(result values.RuntimeValue, label string) (exit bool, value values.RuntimeValue) {
+	switch signal := result.(type) {
+	case *values.BreakValue:
+		if signal.Label == "" || signal.Label == label {
+			return true, &values.NullValue{Type: parser.NodeTypeNull}
+		}
+		return true, signal
+	case *values.ContinueValue:
+		if signal.Label == "" || signal.Label == label {
+			return false, &values.NullValue{Type: parser.NodeTypeNull}
+		}
+		return true, signal
+	case *values.ReturnValue:
+		return true, signal
+	}
+	return false, result
 }
 
-func evaluateReturnStatement(node *parser.ReturnStatement, s *scope.Scope) values.RuntimeValue {
+func evaluateReturnStatement(node *parser.ReturnStatement, s *scope.Scope) (values.RuntimeValue, error) {
 	// If return has no value, return null
 	if node.Value == nil {
 		return &values.ReturnValue{
 			Type:  parser.NodeTypeReturnValue,
 			Value: &values.NullValue{Type: parser.NodeTypeNull},
-		}
+		}, nil
 	}
 
 	// Evaluate the return value
-	value := Evaluate(node.Value, s)
+	value, err := Evaluate(node.Value, s)
+	if err != nil {
+		return nil, err
+	}
 
 	return &values.ReturnValue{
 		Type:  parser.NodeTypeReturnValue,
 		Value: value,
+	}, nil
+}
+
+// evaluateTryStatement runs the try body and, if it fails, the catch body with the
+// error bound as an object. The finally body always runs, and an error or a
+// control-flow signal raised in it wins.
+func evaluateTryStatement(node *parser.TryStatement, s *scope.Scope) (values.RuntimeValue, error) {
+	result, err := evaluateBlock(node.Body, scope.NewScope(s))
+
+	if err != nil && node.HasCatch {
+		catchScope := scope.NewScope(s)
+		if node.CatchVar != "" {
+			if _, declareErr := catchScope.Declare(node.CatchVar, errorToValue(err), false); declareErr != nil {
+				return nil, declareErr
+			}
+		}
+		result, err = evaluateBlock(node.CatchBody, catchScope)
+	}
+
+	if node.FinallyBody != nil {
+		finallyResult, finallyErr := evaluateBlock(node.FinallyBody, scope.NewScope(s))
+		if finallyErr != nil {
+			return nil, finallyErr
+		}
+		// A break, continue or return in the finally body wins as well
+		if isControlFlow(finallyResult) {
+			return finallyResult, nil
+		}
 	}
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// evaluateThrowStatement raises a runtime error carrying the thrown value.
+// Objects can customize the error through their "message" and "kind" properties.
+func evaluateThrowStatement(node *parser.ThrowStatement, s *scope.Scope) (values.RuntimeValue, error) {
+	value, err := Evaluate(node.Value, s)
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprint(value)
+	customKind := ""
+	if object, ok := value.(*values.ObjectValue); ok {
+		if objectMessage, ok := object.Properties["message"].(*values.StringValue); ok {
+			message = objectMessage.Value
+		}
+		if objectKind, ok := object.Properties["kind"].(*values.StringValue); ok {
+			customKind = objectKind.Value
+		}
+	}
+
+	thrown := errors.RuntimeError(node.Token, s.SourceCode(), errors.ErrThrown, message)
+	thrown.Value = value
+	thrown.CustomKind = customKind
+	return nil, thrown
+}
+
+// errorToValue converts an error into the object seen by a catch clause:
+// { message, kind, line, column, file } plus the thrown value, if any.
+func errorToValue(err error) values.RuntimeValue {
+	gloobErr := errors.From(err)
+
+	object := values.NewObjectValue()
+	object.Set("message", &values.StringValue{Type: parser.NodeTypeString, Value: gloobErr.Message})
+	object.Set("kind", &values.StringValue{Type: parser.NodeTypeString, Value: gloobErr.KindName()})
+	object.Set("line", values.NewInt(int64(gloobErr.Line())))
+	object.Set("column", values.NewInt(int64(gloobErr.Column())))
+	object.Set("file", &values.StringValue{Type: parser.NodeTypeString, Value: gloobErr.Filename()})
+	if thrown, ok := gloobErr.Value.(values.RuntimeValue); ok {
+		object.Set("value", thrown)
+	}
+
+	return object
 }
@@ -1,14 +1,21 @@
 package interpreter
 
 import (
-	"fmt"
-	"gloob-interpreter/internal/colors"
+	"gloob-interpreter/internal/builtins"
+	"gloob-interpreter/internal/errors"
+	"gloob-interpreter/internal/lexer"
 	"gloob-interpreter/internal/parser"
 	"gloob-interpreter/internal/scope"
 	"gloob-interpreter/internal/values"
-	"os"
 )
 
+// Native methods such as map and filter call back into Gloob functions through the interpreter.
+func init() {
+	builtins.SetFunctionCaller(func(function values.RuntimeValue, args []values.RuntimeValue, s interface{}) (values.RuntimeValue, error) {
+		return CallFunction(function, args, s.(*scope.Scope))
+	})
+}
+
 // Evaluate is the main dispatch function for the runtime interpreter.
 // It takes any AST node (Statement or Expression) and routes it to the appropriate
 // evaluator function based on the node's type. This is the heart of the interpreter.
@@ -26,21 +33,33 @@ import (
 // - MemberAccess: Accesses object properties
 // - CallExpression: Executes function calls
 // - IfStatement: Executes conditional logic
-func Evaluate(node parser.Statement, s *scope.Scope) values.RuntimeValue {
+//
+// Failures are returned as *errors.Error values so the caller decides how to report them.
+func Evaluate(node parser.Statement, s *scope.Scope) (values.RuntimeValue, error) {
 	switch node.NodeType() {
 	// Literal values - convert directly to runtime values
 	case parser.NodeTypeNumeric:
-		return &values.NumericValue{Type: node.NodeType(), Value: node.(*parser.Numeric).Value}
+		numeric := node.(*parser.Numeric)
+		if numeric.IsInt {
+			return values.NewInt(numeric.Int), nil
+		}
+		return values.NewFloat(numeric.Value), nil
 	case parser.NodeTypeBoolean:
-		return &values.BooleanValue{Type: node.NodeType(), Value: node.(*parser.Boolean).Value}
+		return &values.BooleanValue{Type: node.NodeType(), Value: node.(*parser.Boolean).Value}, nil
 	case parser.NodeTypeNull:
-		return &values.NullValue{Type: parser.NodeTypeNull}
+		return &values.NullValue{Type: parser.NodeTypeNull}, nil
 	case parser.NodeTypeString:
-		return &values.StringValue{Type: parser.NodeTypeString, Value: node.(*parser.String).Value}
+		return &values.StringValue{Type: parser.NodeTypeString, Value: node.(*parser.String).Value}, nil
+	case parser.NodeTypeTemplate:
+		return evaluateTemplateString(node.(*parser.TemplateString), s)
 
 	// Expressions - evaluate recursively
 	case parser.NodeTypeBinaryExpression:
 		return evaluateBinaryExpression(node.(*parser.BinaryExpression), s)
+	case parser.NodeTypeUnaryExpression:
+		return evaluateUnaryExpression(node.(*parser.UnaryExpression), s)
+	case parser.NodeTypeUpdateExpression:
+		return evaluateUpdateExpression(node.(*parser.UpdateExpression), s)
 	case parser.NodeTypeIdentifier:
 		return evaluateIdentifier(node.(*parser.Identifier), s)
 	case parser.NodeTypeObject:
@@ -49,8 +68,12 @@ func Evaluate(node parser.Statement, s *scope.Scope) values.RuntimeValue {
 		return evaluateArray(node.(*parser.Array), s)
 	case parser.NodeTypeArrayIndex:
 		return evaluateArrayIndex(node.(*parser.ArrayIndex), s)
+	case parser.NodeTypeRangeExpression:
+		return evaluateRangeExpression(node.(*parser.RangeExpression), s)
 	case parser.NodeTypeMemberAccess:
 		return evaluateMemberAccess(node.(*parser.MemberAccess), s)
+	case parser.NodeTypeOptionalChain:
+		return evaluateOptionalChain(node.(*parser.OptionalChain), s)
 	case parser.NodeTypeCallExpression:
 		return evaluateCallExpression(node.(*parser.CallExpression), s)
 
@@ -63,21 +86,53 @@ func Evaluate(node parser.Statement, s *scope.Scope) values.RuntimeValue {
 		return evaluateVariableAssignment(node.(*parser.VariableAssignmentExpression), s)
 	case parser.NodeTypeFunctionDeclaration:
 		return evaluateFunctionDeclaration(node.(*parser.FunctionDeclaration), s)
+	case parser.NodeTypeClassDeclaration:
+		return evaluateClassDeclaration(node.(*parser.ClassDeclaration), s)
+	case parser.NodeTypeFunctionExpression:
+		return evaluateFunctionExpression(node.(*parser.FunctionExpression), s)
 	case parser.NodeTypeIfStatement:
 		return evaluateIfStatement(node.(*parser.IfStatement), s)
+	case parser.NodeTypeMatchExpression:
+		return evaluateMatchExpression(node.(*parser.MatchExpression), s)
 	case parser.NodeTypeLoopStatement:
 		return evaluateLoopStatement(node.(*parser.LoopStatement), s)
 	case parser.NodeTypeBreakExpression:
 		return evaluateBreakExpression(node.(*parser.BreakExpression), s)
+	case parser.NodeTypeContinueExpression:
+		return evaluateContinueExpression(node.(*parser.ContinueExpression), s)
 	case parser.NodeTypeReturnStatement:
 		return evaluateReturnStatement(node.(*parser.ReturnStatement), s)
+	case parser.NodeTypeTryStatement:
+		return evaluateTryStatement(node.(*parser.TryStatement), s)
+	case parser.NodeTypeThrowStatement:
+		return evaluateThrowStatement(node.(*parser.ThrowStatement), s)
+	case parser.NodeTypeImportStatement:
+		return evaluateImportStatement(node.(*parser.ImportStatement), s)
+	case parser.NodeTypeExportStatement:
+		return Evaluate(node.(*parser.ExportStatement).Declaration, s)
 	// Native functions - return as-is
 	case parser.NodeTypeNativeFunction:
-		return node.(*values.NativeFunctionValue)
+		return node.(*values.NativeFunctionValue), nil
 
 	default:
-		fmt.Printf("Unknown node type: %s, i don't know what to tell you 🫣\n", colors.Red(node.NodeType()))
-		os.Exit(1)
-		return nil
+		return nil, runtimeError(s, nil, errors.ErrUnknownNodeType, node.NodeType())
 	}
 }
+
+// CallFunction calls a Gloob function value (user-defined or native) with the given arguments.
+// It lets Go code, such as embedding hosts, call back into the interpreter.
+func CallFunction(function values.RuntimeValue, args []values.RuntimeValue, s *scope.Scope) (values.RuntimeValue, error) {
+	return callFunction(function, args, nil, nil, s)
+}
+
+// runtimeError creates a runtime error located at token,
+// using the source code of the scope to show the offending line.
+func runtimeError(s *scope.Scope, token *lexer.Token, kind errors.Kind, args ...interface{}) error {
+	return errors.RuntimeError(token, s.SourceCode(), kind, args...)
+}
+
+// locate attaches the position of token to errors coming from places that
+// don't know where they happened, such as the scope or native functions.
+func locate(err error, token *lexer.Token, s *scope.Scope) error {
+	return errors.Locate(err, token, s.SourceCode())
+}
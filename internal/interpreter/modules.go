@@ -0,0 +1,153 @@
+package interpreter
+
+import (
+	"gloob-interpreter/internal/errors"
+	"gloob-interpreter/internal/imports"
+	"gloob-interpreter/internal/lexer"
+	"gloob-interpreter/internal/parser"
+	"gloob-interpreter/internal/scope"
+	"gloob-interpreter/internal/values"
+	"strings"
+)
+
+// evaluateImportStatement imports a module and declares what the statement asks for as constants:
+// the module object (import "path" as m), some of its exports (from "path" import a, b)
+// or all of them (import "path").
+func evaluateImportStatement(node *parser.ImportStatement, s *scope.Scope) (values.RuntimeValue, error) {
+	exports, err := importModule(node, s)
+	if err != nil {
+		return nil, err
+	}
+
+	declare := func(name string, value values.RuntimeValue, token *lexer.Token) error {
+		if _, err := s.Declare(name, value, true); err != nil {
+			return locate(err, token, s)
+		}
+		return nil
+	}
+
+	switch {
+	case node.Alias != "":
+		if err := declare(node.Alias, exports, node.Token); err != nil {
+			return nil, err
+		}
+	case node.Names != nil:
+		for _, name := range node.Names {
+			value, ok := exports.Get(name.Name)
+			if !ok {
+				return nil, runtimeError(s, name.Token, errors.ErrNotExported, node.Path, name.Name)
+			}
+			if err := declare(name.Name, value, name.Token); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		for _, key := range exports.Keys() {
+			if err := declare(key, exports.Properties[key], node.Token); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &values.NullValue{Type: parser.NodeTypeNull}, nil
+}
+
+// importModule returns the exports of the module imported by node.
+// The module is evaluated the first time it is imported, in its own scope, and cached for the next imports.
+func importModule(node *parser.ImportStatement, s *scope.Scope) (*values.ObjectValue, error) {
+	module, err := imports.Resolve(node.Path, node.Token.Filename)
+	if err != nil {
+		return nil, runtimeError(s, node.Token, errors.ErrImportFailed, node.Path, err)
+	}
+
+	modules := s.Modules()
+	if exports, ok := modules.Exports(module.Key); ok {
+		return exports, nil
+	}
+	if chain, ok := modules.Start(module.Key, module.Path); !ok {
+		return nil, runtimeError(s, node.Token, errors.ErrCircularImport, node.Path, strings.Join(chain, " -> "))
+	}
+
+	var exports *values.ObjectValue
+	defer func() { modules.Finish(module.Key, exports) }()
+
+	program, sourceCode, err := imports.Load(module)
+	if err != nil {
+		if _, ok := err.(*errors.Error); ok {
+			return nil, err
+		}
+		return nil, runtimeError(s, node.Token, errors.ErrImportFailed, node.Path, err)
+	}
+
+	moduleScope := scope.NewModuleScope(s)
+	moduleScope.SetSourceCode(sourceCode)
+	if _, err := Evaluate(program, moduleScope); err != nil {
+		return nil, err
+	}
+
+	exports = moduleExports(program, moduleScope)
+	return exports, nil
+}
+
+// moduleExports collects the values exported by a module: its declarations marked with export,
+// or all of its top level declarations when nothing is marked.
+func moduleExports(program *parser.Program, s *scope.Scope) *values.ObjectValue {
+	var exported, declared []string
+	for _, statement := range program.Statements {
+		if export, ok := statement.(*parser.ExportStatement); ok {
+			exported = append(exported, declaredNames(export.Declaration)...)
+		} else {
+			declared = append(declared, declaredNames(statement)...)
+		}
+	}
+	if exported == nil {
+		exported = declared
+	}
+
+	exports := values.NewObjectValue()
+	for _, name := range exported {
+		value, err := s.Get(name)
+		if err == nil {
+			exports.Set(name, value)
+		}
+	}
+	return exports
+}
+
+// declaredNames returns the names declared by a statement.
+func declaredNames(statement parser.Statement) []string {
+	switch declaration := statement.(type) {
+	case *parser.VariableDeclaration:
+		if declaration.Pattern != nil {
+			return patternNames(declaration.Pattern)
+		}
+		return []string{declaration.Identifier}
+	case *parser.FunctionDeclaration:
+		return []string{declaration.Identifier}
+	case *parser.ClassDeclaration:
+		return []string{declaration.Name}
+	}
+	return nil
+}
+
+// patternNames returns the names bound by a destructuring pattern.
+func patternNames(pattern parser.Expression) []string {
+	var names []string
+	switch pattern := pattern.(type) {
+	case *parser.Identifier:
+		if pattern.Name != "_" {
+			names = append(names, pattern.Name)
+		}
+	case *parser.SpreadElement:
+		names = append(names, patternNames(pattern.Argument)...)
+	case *parser.Array:
+		for _, element := range pattern.Elements {
+			names = append(names, patternNames(element)...)
+		}
+	case *parser.Object:
+		for _, property := range pattern.Properties {
+			names = append(names, patternNames(property.Value)...)
+		}
+	}
+	return names
+}
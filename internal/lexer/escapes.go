@@ -0,0 +1,105 @@
+package lexer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// escapes maps the character after a backslash to the character it stands for.
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'\\': '\\',
+	'"':  '"',
+	'\'': '\'',
+	'`':  '`',
+	'$':  '$',
+}
+
+// Unescape replaces the escape sequences of a string literal with the characters they stand for.
+// Supported sequences are \n \t \r \0 \\ \" \' \` \$, \uXXXX and \u{X...} for unicode code points.
+// Unknown sequences are kept as written, so "\d" stays a backslash followed by a d.
+func Unescape(raw string) string {
+	if !strings.ContainsRune(raw, '\\') {
+		return raw
+	}
+
+	chars := []rune(raw)
+	var result strings.Builder
+	for i := 0; i < len(chars); i++ {
+		if chars[i] != '\\' || i+1 == len(chars) {
+			result.WriteRune(chars[i])
+			continue
+		}
+
+		if escaped, ok := escapes[chars[i+1]]; ok {
+			result.WriteRune(escaped)
+			i++
+			continue
+		}
+
+		if chars[i+1] == 'u' {
+			if codePoint, length, ok := unicodeEscape(chars[i+2:]); ok {
+				result.WriteRune(codePoint)
+				i += 1 + length
+				continue
+			}
+		}
+
+		result.WriteRune(chars[i])
+	}
+	return result.String()
+}
+
+// unicodeEscape reads the code point of a \u escape: either 4 hex digits or {hex digits}.
+// It returns the code point and how many characters after the 'u' it used.
+func unicodeEscape(chars []rune) (rune, int, bool) {
+	digits, length := "", 4
+	if len(chars) > 0 && chars[0] == '{' {
+		end := 1
+		for end < len(chars) && chars[end] != '}' {
+			end++
+		}
+		if end == len(chars) {
+			return 0, 0, false
+		}
+		digits, length = string(chars[1:end]), end+1
+	} else if len(chars) >= 4 {
+		digits = string(chars[:4])
+	}
+
+	codePoint, err := strconv.ParseUint(digits, 16, 32)
+	if digits == "" || err != nil || codePoint > 0x10FFFF {
+		return 0, 0, false
+	}
+	return rune(codePoint), length, true
+}
+
+// EmbeddedExpressionEnd returns the index of the '}' closing the ${ expression that
+// starts at chars[start], or len(chars) when it is not closed.
+// Braces inside the expression and inside its string literals are skipped.
+func EmbeddedExpressionEnd(chars []rune, start int) int {
+	depth := 1
+	for i := start; i < len(chars); i++ {
+		switch chars[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"', '\'', '`':
+			// Skip string literals, including their escaped quotes
+			quote := chars[i]
+			for i++; i < len(chars) && chars[i] != quote; i++ {
+				if chars[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return len(chars)
+}
@@ -11,6 +11,7 @@ var Keywords = map[string]TokenType{
 	"break":    TokenTypeBreak,
 	"continue": TokenTypeContinue,
 	"import":   TokenTypeImport,
+	"export":   TokenTypeExport,
 	"true":     TokenTypeTrue,
 	"false":    TokenTypeFalse,
 	"yes":      TokenTypeYes,
@@ -21,4 +22,11 @@ var Keywords = map[string]TokenType{
 	"to":       TokenTypeTo,
 	"null":     TokenTypeNull,
 	"fun":      TokenTypeFunction,
+	"try":      TokenTypeTry,
+	"catch":    TokenTypeCatch,
+	"finally":  TokenTypeFinally,
+	"throw":    TokenTypeThrow,
+	"match":    TokenTypeMatch,
+	"class":    TokenTypeClass,
+	"is":       TokenTypeIs,
 }
@@ -1,6 +1,9 @@
 package lexer
 
-import "unicode"
+import (
+	"strings"
+	"unicode"
+)
 
 type Token struct {
 	Type        TokenType
@@ -25,12 +28,22 @@ func CaptureToken(literal string, tokenType TokenType, line int, columnStart int
 type Lexer struct {
 	input    string
 	filename string
+	line     int // Line of the first character of input
+	column   int // Column of the first character of input
 }
 
 func NewLexer(input string, filename string) *Lexer {
+	return NewLexerAt(input, filename, 1, 1)
+}
+
+// NewLexerAt creates a lexer for a piece of a bigger source, such as an expression
+// embedded in a template string, so its tokens report their real position.
+func NewLexerAt(input string, filename string, line int, column int) *Lexer {
 	return &Lexer{
 		input:    input,
 		filename: filename,
+		line:     line,
+		column:   column,
 	}
 }
 
@@ -38,8 +51,8 @@ func (l *Lexer) Tokenize() []Token {
 	tokens := []Token{}
 	chars := []rune(l.input)
 
-	line := 1
-	column := 1
+	line := l.line
+	column := l.column
 
 	for len(chars) > 0 {
 		ch := chars[0]
@@ -63,9 +76,9 @@ func (l *Lexer) Tokenize() []Token {
 		tokenType := TokenTypeUnknown
 		literal := string(ch)
 
-		if unicode.IsLetter(ch) {
+		if unicode.IsLetter(ch) || ch == '_' {
 			literal = ""
-			for len(chars) > 0 && (unicode.IsLetter(chars[0]) || unicode.IsDigit(chars[0])) {
+			for len(chars) > 0 && (unicode.IsLetter(chars[0]) || unicode.IsDigit(chars[0]) || chars[0] == '_') {
 				literal += string(chars[0])
 				chars = chars[1:]
 				column++
@@ -79,31 +92,87 @@ func (l *Lexer) Tokenize() []Token {
 			continue
 		}
 
-		// Handle negative numbers: check if '-' is followed by a digit
-		if ch == '-' && len(chars) > 1 && unicode.IsDigit(chars[1]) {
-			literal = string(ch)
-			chars = chars[1:] // consume the '-'
-			column++
-			// Continue to parse as number
-			for len(chars) > 0 && (unicode.IsDigit(chars[0]) || chars[0] == '.') {
-				literal += string(chars[0])
-				chars = chars[1:]
-				column++
-			}
-			tokenType = TokenTypeNumber
+		if unicode.IsDigit(ch) {
+			literal, tokenType = scanNumber(chars)
+			chars = chars[len([]rune(literal)):]
+			column += len([]rune(literal))
 			tokens = append(tokens, CaptureToken(literal, tokenType, line, columnStart, column-1, l.filename))
 			continue
 		}
 
-		if unicode.IsDigit(ch) {
+		// Handle triple-quoted strings: they can span several lines and are raw (no escape sequences)
+		if (ch == '"' || ch == '\'') && len(chars) > 2 && chars[1] == ch && chars[2] == ch {
+			startLine := line
 			literal = ""
-			for len(chars) > 0 && (unicode.IsDigit(chars[0]) || chars[0] == '.') {
+			chars = chars[3:] // consume opening quotes
+			column += 3
+
+			closed := false
+			for len(chars) > 0 {
+				if len(chars) > 2 && chars[0] == ch && chars[1] == ch && chars[2] == ch {
+					closed = true
+					break
+				}
+				if chars[0] == '\n' {
+					line++
+					column = 1
+				} else {
+					column++
+				}
 				literal += string(chars[0])
 				chars = chars[1:]
-				column++
 			}
-			tokenType = TokenTypeNumber
-			tokens = append(tokens, CaptureToken(literal, tokenType, line, columnStart, column-1, l.filename))
+
+			if !closed {
+				// Unterminated string
+				tokens = append(tokens, CaptureToken(literal, TokenTypeUnknown, startLine, columnStart, column-1, l.filename))
+				continue
+			}
+
+			chars = chars[3:] // consume closing quotes
+			column += 3
+			// A line break right after the opening quotes is not part of the string
+			literal = strings.TrimPrefix(strings.TrimPrefix(literal, "\r"), "\n")
+			tokens = append(tokens, CaptureToken(literal, TokenTypeString, startLine, columnStart, column-1, l.filename))
+			continue
+		}
+
+		// Handle template strings: `Hello ${name}`.
+		// The literal keeps the raw content, the parser splits it into text and expressions.
+		if ch == '`' {
+			startLine := line
+			literal = ""
+			chars = chars[1:] // consume opening backtick
+			column++
+
+			for len(chars) > 0 && chars[0] != '`' {
+				length := 1
+				if chars[0] == '\\' && len(chars) > 1 {
+					length = 2
+				} else if chars[0] == '$' && len(chars) > 1 && chars[1] == '{' {
+					length = min(EmbeddedExpressionEnd(chars, 2)+1, len(chars))
+				}
+				for _, r := range chars[:length] {
+					if r == '\n' {
+						line++
+						column = 1
+					} else {
+						column++
+					}
+				}
+				literal += string(chars[:length])
+				chars = chars[length:]
+			}
+
+			if len(chars) == 0 {
+				// Unterminated template string
+				tokens = append(tokens, CaptureToken(literal, TokenTypeUnknown, startLine, columnStart, column-1, l.filename))
+				continue
+			}
+
+			chars = chars[1:] // consume closing backtick
+			column++
+			tokens = append(tokens, CaptureToken(literal, TokenTypeTemplate, startLine, columnStart, column-1, l.filename))
 			continue
 		}
 
@@ -115,6 +184,12 @@ func (l *Lexer) Tokenize() []Token {
 			column++
 
 			for len(chars) > 0 && chars[0] != quoteChar {
+				// Keep escape sequences together so an escaped quote doesn't end the string
+				if chars[0] == '\\' && len(chars) > 1 {
+					literal += string(chars[0])
+					chars = chars[1:]
+					column++
+				}
 				literal += string(chars[0])
 				chars = chars[1:]
 				column++
@@ -129,18 +204,23 @@ func (l *Lexer) Tokenize() []Token {
 			chars = chars[1:] // consume closing quote
 			column++
 			tokenType = TokenTypeString
-			tokens = append(tokens, CaptureToken(literal, tokenType, line, columnStart, column-1, l.filename))
+			tokens = append(tokens, CaptureToken(Unescape(literal), tokenType, line, columnStart, column-1, l.filename))
 			continue
 		}
 
 		switch ch {
 		case '=':
-			// Check for == operator
+			// Check for == and => operators
 			if len(chars) > 1 && chars[1] == '=' {
 				literal = "=="
 				tokenType = TokenTypeEqualEqual
 				chars = chars[1:] // consume second =
 				column++
+			} else if len(chars) > 1 && chars[1] == '>' {
+				literal = "=>"
+				tokenType = TokenTypeArrow
+				chars = chars[1:] // consume >
+				column++
 			} else {
 				tokenType = TokenTypeEqual
 			}
@@ -175,12 +255,35 @@ func (l *Lexer) Tokenize() []Token {
 				tokenType = TokenTypeLessThan
 			}
 		case '+', '-', '*', '%':
-			tokenType = TokenTypeOperator
+			// Check for ++, -- and compound assignments (+=, -=, *=, %=)
+			if len(chars) > 1 && (ch == '+' || ch == '-') && chars[1] == ch {
+				literal = string(ch) + string(ch)
+				tokenType = TokenTypeIncrement
+				chars = chars[1:] // consume second + or -
+				column++
+			} else if len(chars) > 1 && chars[1] == '=' {
+				literal = string(ch) + "="
+				tokenType = TokenTypeCompoundAssignment
+				chars = chars[1:] // consume =
+				column++
+			} else {
+				tokenType = TokenTypeOperator
+			}
 		case '/':
 			if len(chars) > 1 && chars[1] == '/' {
-				literal = "//"
+				// A comment runs to the end of the line, quotes inside it don't start strings
+				length := 2
+				for length < len(chars) && chars[length] != '\n' {
+					length++
+				}
+				literal = string(chars[:length])
 				tokenType = TokenTypeComment
-				chars = chars[1:] // consume /
+				chars = chars[length-1:] // consume the comment but its last character
+				column += length - 1
+			} else if len(chars) > 1 && chars[1] == '=' {
+				literal = "/="
+				tokenType = TokenTypeCompoundAssignment
+				chars = chars[1:] // consume =
 				column++
 			} else {
 				tokenType = TokenTypeOperator
@@ -204,7 +307,20 @@ func (l *Lexer) Tokenize() []Token {
 		case ',':
 			tokenType = TokenTypeComma
 		case '.':
-			tokenType = TokenTypeDot
+			// Check for ... (rest parameters and spread arguments) and .. (ranges)
+			if len(chars) > 2 && chars[1] == '.' && chars[2] == '.' {
+				literal = "..."
+				tokenType = TokenTypeEllipsis
+				chars = chars[2:] // consume the other two dots
+				column += 2
+			} else if len(chars) > 1 && chars[1] == '.' {
+				literal = ".."
+				tokenType = TokenTypeRange
+				chars = chars[1:] // consume second .
+				column++
+			} else {
+				tokenType = TokenTypeDot
+			}
 		case '&':
 			if len(chars) > 1 && chars[1] == '&' {
 				literal = "&&"
@@ -223,6 +339,19 @@ func (l *Lexer) Tokenize() []Token {
 			} else {
 				tokenType = TokenTypePipe
 			}
+		case '?':
+			// Only ?? and ?. exist, a lone ? is unknown
+			if len(chars) > 1 && chars[1] == '?' {
+				literal = "??"
+				tokenType = TokenTypeNullCoalescing
+				chars = chars[1:] // consume second ?
+				column++
+			} else if len(chars) > 1 && chars[1] == '.' {
+				literal = "?."
+				tokenType = TokenTypeOptionalChain
+				chars = chars[1:] // consume .
+				column++
+			}
 		default:
 			tokenType = TokenTypeUnknown
 		}
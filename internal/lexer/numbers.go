@@ -0,0 +1,40 @@
+package lexer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scanNumber reads the number literal at the start of chars and returns it with its token type.
+// Ints can be written in decimal, hex (0xFF), binary (0b1010) or octal (0o755), floats with a
+// decimal point and/or an exponent (2.5e-3), and underscores can separate digits (1_000_000).
+// Everything glued to the number (letters, digits, underscores and dots followed by a digit)
+// is part of the literal, so malformed numbers like 1.2.3, 0b102 or 12abc reach the parser
+// whole and are reported there as a syntax error.
+func scanNumber(chars []rune) (string, TokenType) {
+	prefixed := len(chars) > 1 && chars[0] == '0' && strings.ContainsRune("xXbBoO", chars[1])
+
+	end := 0
+scan:
+	for end < len(chars) {
+		ch := chars[end]
+		switch {
+		case ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch):
+			end++
+		case ch == '.' && end+1 < len(chars) && unicode.IsDigit(chars[end+1]):
+			end++
+		case (ch == '+' || ch == '-') && !prefixed && (chars[end-1] == 'e' || chars[end-1] == 'E') &&
+			end+1 < len(chars) && unicode.IsDigit(chars[end+1]):
+			end++ // Sign of an exponent, as in 2.5e-3
+		default:
+			break scan
+		}
+	}
+
+	// A decimal point or an exponent makes the literal a float, otherwise it is an int
+	literal := string(chars[:end])
+	if !prefixed && strings.ContainsAny(literal, ".eE") {
+		return literal, TokenTypeFloat
+	}
+	return literal, TokenTypeInteger
+}
@@ -24,17 +24,26 @@ const (
 	TokenTypeAnd                 TokenType = "AND"
 	TokenTypeOr                  TokenType = "OR"
 	TokenTypeDot                 TokenType = "DOT"
+	TokenTypeEllipsis            TokenType = "ELLIPSIS"
+	TokenTypeRange               TokenType = "RANGE"
 	TokenTypeComma               TokenType = "COMMA"
 	TokenTypePipe                TokenType = "PIPE"
 	TokenTypeExclamation         TokenType = "EXCLAMATION"
+	TokenTypeArrow               TokenType = "ARROW"
+	TokenTypeCompoundAssignment  TokenType = "COMPOUND_ASSIGNMENT"
+	TokenTypeIncrement           TokenType = "INCREMENT"
+	TokenTypeNullCoalescing      TokenType = "NULL_COALESCING"
+	TokenTypeOptionalChain       TokenType = "OPTIONAL_CHAIN"
 	TokenTypeNewline             TokenType = "NEWLINE"
 	TokenTypeComment             TokenType = "COMMENT"
 
 	// Tokens
-	TokenTypeNumber     TokenType = "NUMBER"
+	TokenTypeInteger    TokenType = "INTEGER"
+	TokenTypeFloat      TokenType = "FLOAT"
 	TokenTypeIdentifier TokenType = "IDENTIFIER"
 	TokenTypeUnknown    TokenType = "UNKNOWN"
 	TokenTypeString     TokenType = "STRING"
+	TokenTypeTemplate   TokenType = "TEMPLATE"
 	TokenTypeBoolean    TokenType = "BOOLEAN"
 	TokenTypeNull       TokenType = "NULL"
 
@@ -47,6 +56,7 @@ const (
 	TokenTypeBreak    TokenType = "BREAK"
 	TokenTypeContinue TokenType = "CONTINUE"
 	TokenTypeImport   TokenType = "IMPORT"
+	TokenTypeExport   TokenType = "EXPORT"
 	TokenTypeVar      TokenType = "VAR"
 	TokenTypeConst    TokenType = "CONST"
 	TokenTypeFrom     TokenType = "FROM"
@@ -57,6 +67,13 @@ const (
 	TokenTypeNo       TokenType = "NO"
 	TokenTypeOn       TokenType = "ON"
 	TokenTypeOff      TokenType = "OFF"
+	TokenTypeTry      TokenType = "TRY"
+	TokenTypeCatch    TokenType = "CATCH"
+	TokenTypeFinally  TokenType = "FINALLY"
+	TokenTypeThrow    TokenType = "THROW"
+	TokenTypeMatch    TokenType = "MATCH"
+	TokenTypeClass    TokenType = "CLASS"
+	TokenTypeIs       TokenType = "IS"
 
 	// Special tokens
 	TokenTypeEOF TokenType = "EOF"
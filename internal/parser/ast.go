@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"gloob-interpreter/internal/lexer"
+	"strings"
 )
 
 // NodeType represents the type of an AST node.
@@ -15,45 +16,60 @@ const (
 	NodeTypeProgram NodeType = "PROGRAM"
 
 	// Literal value nodes
-	NodeTypeNumeric NodeType = "NUMERIC" // Number literals (e.g., 42, 3.14)
-	NodeTypeBoolean NodeType = "BOOLEAN" // Boolean literals (true, false)
-	NodeTypeString  NodeType = "STRING"  // String literals (e.g., "hello")
-	NodeTypeNull    NodeType = "NULL"    // Null value
+	NodeTypeNumeric  NodeType = "NUMERIC"  // Number literals (e.g., 42, 3.14)
+	NodeTypeBoolean  NodeType = "BOOLEAN"  // Boolean literals (true, false)
+	NodeTypeString   NodeType = "STRING"   // String literals (e.g., "hello")
+	NodeTypeTemplate NodeType = "TEMPLATE" // Template strings (e.g., `Hello ${name}`)
+	NodeTypeNull     NodeType = "NULL"     // Null value
 
 	// Identifier and expression nodes
 	NodeTypeIdentifier       NodeType = "IDENTIFIER"        // Variable/function names
 	NodeTypeBinaryExpression NodeType = "BINARY_EXPRESSION" // Binary operations (+, -, *, /, ==, etc.)
-	NodeTypeUnaryExpression  NodeType = "UNARY_EXPRESSION"  // Unary operations (not implemented yet)
+	NodeTypeUnaryExpression  NodeType = "UNARY_EXPRESSION"  // Unary operations (!, -)
+	NodeTypeUpdateExpression NodeType = "UPDATE_EXPRESSION" // Increments and decrements (x++, --x)
 
 	// Object-related nodes
-	NodeTypeObject       NodeType = "OBJECT"        // Object literals { key: value }
-	NodeTypeProperty     NodeType = "PROPERTY"      // Object properties
-	NodeTypeMemberAccess NodeType = "MEMBER_ACCESS" // Property access (obj.property)
+	NodeTypeObject        NodeType = "OBJECT"         // Object literals { key: value }
+	NodeTypeProperty      NodeType = "PROPERTY"       // Object properties
+	NodeTypeMemberAccess  NodeType = "MEMBER_ACCESS"  // Property access (obj.property)
+	NodeTypeOptionalChain NodeType = "OPTIONAL_CHAIN" // Chains with optional links (obj?.property)
 
 	// Function-related nodes
 	NodeTypeCallExpression      NodeType = "CALL_EXPRESSION"      // Function calls func(args)
 	NodeTypeFunctionDeclaration NodeType = "FUNCTION_DECLARATION" // Function definitions
+	NodeTypeFunctionExpression  NodeType = "FUNCTION_EXPRESSION"  // Anonymous functions fun (x) { } and (x) => x
 	NodeTypeNativeFunction      NodeType = "NATIVE_FUNCTION"      // Built-in functions
+	NodeTypeSpreadElement       NodeType = "SPREAD_ELEMENT"       // Spread arguments f(...args)
+	NodeTypeNamedArgument       NodeType = "NAMED_ARGUMENT"       // Named arguments f(name: value)
+	NodeTypeClassDeclaration    NodeType = "CLASS_DECLARATION"    // Class definitions
+	NodeTypeClass               NodeType = "CLASS"                // Classes created by class definitions
 
 	// Variable-related nodes
 	NodeTypeVariableDeclaration NodeType = "VARIABLE_DECLARATION" // var/const declarations
 	NodeTypeVariableAssignment  NodeType = "VARIABLE_ASSIGNMENT"  // Variable assignments (var = value)
 
 	// Control flow nodes
-	NodeTypeIfStatement     NodeType = "IF_STATEMENT"     // if statements
-	NodeTypeElseIfClause    NodeType = "ELSE_IF_CLAUSE"   // elseif clauses
-	NodeTypeLoopStatement   NodeType = "LOOP_STATEMENT"   // loop statements
-	NodeTypeBreakExpression NodeType = "BREAK_EXPRESSION" // break statements
-	NodeTypeReturnStatement NodeType = "RETURN_STATEMENT" // return statements
-	NodeTypeReturnValue     NodeType = "RETURN_VALUE"     // return value (runtime marker)
+	NodeTypeIfStatement        NodeType = "IF_STATEMENT"        // if statements
+	NodeTypeMatchExpression    NodeType = "MATCH_EXPRESSION"    // match expressions
+	NodeTypeElseIfClause       NodeType = "ELSE_IF_CLAUSE"      // elseif clauses
+	NodeTypeLoopStatement      NodeType = "LOOP_STATEMENT"      // loop statements
+	NodeTypeBreakExpression    NodeType = "BREAK_EXPRESSION"    // break statements
+	NodeTypeContinueExpression NodeType = "CONTINUE_EXPRESSION" // continue statements
+	NodeTypeReturnStatement    NodeType = "RETURN_STATEMENT"    // return statements
+	NodeTypeReturnValue        NodeType = "RETURN_VALUE"        // return value (runtime marker)
+	NodeTypeTryStatement       NodeType = "TRY_STATEMENT"       // try/catch/finally statements
+	NodeTypeThrowStatement     NodeType = "THROW_STATEMENT"     // throw statements
 
 	// Import nodes
 	NodeTypeImportStatement NodeType = "IMPORT_STATEMENT" // import statements
+	NodeTypeExportStatement NodeType = "EXPORT_STATEMENT" // export declarations
 
 	// Collection nodes
-	NodeTypeArray      NodeType = "ARRAY"       // Array literals [1, 2, 3]
-	NodeTypeArrayIndex NodeType = "ARRAY_INDEX" // Array indexing arr[1]
-	NodeTypeCollection NodeType = "COLLECTION"  // Generic collections (not implemented)
+	NodeTypeArray           NodeType = "ARRAY"            // Array literals [1, 2, 3]
+	NodeTypeArrayIndex      NodeType = "ARRAY_INDEX"      // Array indexing arr[1] and slicing arr[2..4]
+	NodeTypeRangeExpression NodeType = "RANGE_EXPRESSION" // Range expressions 1..10:2
+	NodeTypeRange           NodeType = "RANGE"            // Ranges created by range expressions
+	NodeTypeCollection      NodeType = "COLLECTION"       // Generic collections (not implemented)
 )
 
 // Statement represents any executable statement in the language.
@@ -72,6 +88,7 @@ type Expression interface {
 // It contains all the statements that make up a Gloob program.
 type Program struct {
 	Statements []Statement // All statements in the program
+	Filename   string      // File the program was parsed from
 }
 
 func (p *Program) NodeType() NodeType {
@@ -79,11 +96,13 @@ func (p *Program) NodeType() NodeType {
 }
 
 // VariableDeclaration represents variable and constant declarations.
-// Examples: var name = "value", const PI = 3.14
+// Examples: var name = "value", const PI = 3.14, var [first, ...rest] = list, var { name, age: years } = user
 type VariableDeclaration struct {
-	Constant   bool       // true for const, false for var
-	Identifier string     // Variable name
-	Value      Expression // Initial value (can be nil for var without assignment)
+	Constant   bool         // true for const, false for var
+	Identifier string       // Variable name ("" when Pattern is set)
+	Pattern    Expression   // Array or object pattern of a destructuring declaration (nil for a single variable)
+	Value      Expression   // Initial value (can be nil for var without assignment)
+	Token      *lexer.Token // Identifier token (or the opening bracket of the pattern) for error reporting
 }
 
 func (v *VariableDeclaration) NodeType() NodeType {
@@ -93,10 +112,11 @@ func (v *VariableDeclaration) NodeType() NodeType {
 // BinaryExpression represents binary operations like arithmetic and comparison.
 // Examples: a + b, x > y, name == "test"
 type BinaryExpression struct {
-	Type     NodeType   `json:"type"`     // Node type (always BINARY_EXPRESSION)
-	Left     Expression `json:"left"`     // Left operand
-	Operator string     `json:"operator"` // Operator (+, -, *, /, ==, !=, >, <, etc.)
-	Right    Expression `json:"right"`    // Right operand
+	Type     NodeType     `json:"type"`     // Node type (always BINARY_EXPRESSION)
+	Left     Expression   `json:"left"`     // Left operand
+	Operator string       `json:"operator"` // Operator (+, -, *, /, ==, !=, >, <, etc.)
+	Right    Expression   `json:"right"`    // Right operand
+	Token    *lexer.Token `json:"-"`        // Operator token for error reporting
 }
 
 func (b *BinaryExpression) NodeType() NodeType {
@@ -107,6 +127,45 @@ func (b *BinaryExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", b.Left, b.Operator, b.Right)
 }
 
+// UnaryExpression represents operations with a single operand placed after the operator.
+// Examples: !done, -x, -(a + b), !list.contains(v)
+type UnaryExpression struct {
+	Type     NodeType     `json:"type"`     // Node type (always UNARY_EXPRESSION)
+	Operator string       `json:"operator"` // Operator (! or -)
+	Operand  Expression   `json:"operand"`  // The expression the operator applies to
+	Token    *lexer.Token `json:"-"`        // Operator token for error reporting
+}
+
+func (u *UnaryExpression) NodeType() NodeType {
+	return NodeTypeUnaryExpression
+}
+
+func (u *UnaryExpression) String() string {
+	return fmt.Sprintf("(%s%s)", u.Operator, u.Operand)
+}
+
+// UpdateExpression represents increments and decrements of an assignable target.
+// The prefix form evaluates to the new value, the postfix form to the value before the update.
+// Examples: count++, --lives, obj.total++, arr[i]--
+type UpdateExpression struct {
+	Type     NodeType     `json:"type"`     // Node type (always UPDATE_EXPRESSION)
+	Operator string       `json:"operator"` // Operator (++ or --)
+	Target   Expression   `json:"target"`   // Identifier, MemberAccess or ArrayIndex being updated
+	Prefix   bool         `json:"prefix"`   // True for ++x, false for x++
+	Token    *lexer.Token `json:"-"`        // Operator token for error reporting
+}
+
+func (u *UpdateExpression) NodeType() NodeType {
+	return NodeTypeUpdateExpression
+}
+
+func (u *UpdateExpression) String() string {
+	if u.Prefix {
+		return fmt.Sprintf("(%s%s)", u.Operator, u.Target)
+	}
+	return fmt.Sprintf("(%s%s)", u.Target, u.Operator)
+}
+
 // Identifier represents variable and function names.
 // Examples: name, age, calculateSum
 type Identifier struct {
@@ -123,11 +182,13 @@ func (i *Identifier) String() string {
 	return i.Name
 }
 
-// Numeric represents number literals.
+// Numeric represents number literals, either ints or floats.
 // Examples: 42, 3.14, -10
 type Numeric struct {
 	Type  NodeType `json:"type"`  // Node type (always NUMERIC)
-	Value float64  `json:"value"` // The numeric value
+	Value float64  `json:"value"` // The numeric value (converted to float64 for ints)
+	Int   int64    `json:"int"`   // The exact value of an int literal
+	IsInt bool     `json:"isInt"` // True for int literals (42), false for float literals (42.0)
 }
 
 func (n *Numeric) NodeType() NodeType {
@@ -135,6 +196,9 @@ func (n *Numeric) NodeType() NodeType {
 }
 
 func (n *Numeric) String() string {
+	if n.IsInt {
+		return fmt.Sprintf("%d", n.Int)
+	}
 	return fmt.Sprintf("%g", n.Value)
 }
 
@@ -184,11 +248,37 @@ func (s *String) String() string {
 	return s.Value
 }
 
+// TemplateString represents template strings with embedded expressions.
+// Parts holds the literal text as String nodes and the embedded expressions, in order.
+// Examples: `Hello ${name}`, `Total: ${price * quantity}`
+type TemplateString struct {
+	Parts []Expression // Text and expressions, concatenated at runtime
+	Token *lexer.Token // Template token for error reporting
+}
+
+func (t *TemplateString) NodeType() NodeType {
+	return NodeTypeTemplate
+}
+
+func (t *TemplateString) String() string {
+	result := "`"
+	for _, part := range t.Parts {
+		if text, ok := part.(*String); ok {
+			result += text.Value
+		} else {
+			result += fmt.Sprintf("${%s}", part)
+		}
+	}
+	return result + "`"
+}
+
 // VariableAssignmentExpression represents assignment operations.
-// Examples: name = "value", obj.property = 42
+// Examples: name = "value", obj.property = 42, count += 1, arr[i] *= 2
 type VariableAssignmentExpression struct {
-	Identifier Expression // Can be Identifier or MemberAccess
-	Value      Expression // The value being assigned
+	Identifier Expression   // Can be Identifier, MemberAccess, ArrayIndex, or an Array or Object pattern ([a, b] = [b, a])
+	Value      Expression   // The value being assigned
+	Operator   string       // "=" or a compound assignment operator (+=, -=, *=, /=, %=)
+	Token      *lexer.Token // Operator token for error reporting
 }
 
 func (a *VariableAssignmentExpression) NodeType() NodeType {
@@ -196,7 +286,10 @@ func (a *VariableAssignmentExpression) NodeType() NodeType {
 }
 
 func (a *VariableAssignmentExpression) String() string {
-	return fmt.Sprintf("%s = %s", a.Identifier, a.Value)
+	if a.Operator == "" {
+		return fmt.Sprintf("%s = %s", a.Identifier, a.Value)
+	}
+	return fmt.Sprintf("%s %s %s", a.Identifier, a.Operator, a.Value)
 }
 
 // Object represents object literals.
@@ -231,8 +324,10 @@ func (p *Property) String() string {
 // MemberAccess represents property access on objects.
 // Examples: obj.name, person.address.city
 type MemberAccess struct {
-	Object   Expression // The object being accessed
-	Property string     // The property name
+	Object   Expression   // The object being accessed
+	Property string       // The property name
+	Optional bool         // True for obj?.property, which gives null when the object is null
+	Token    *lexer.Token // Property token for error reporting
 }
 
 func (m *MemberAccess) NodeType() NodeType {
@@ -240,15 +335,20 @@ func (m *MemberAccess) NodeType() NodeType {
 }
 
 func (m *MemberAccess) String() string {
+	if m.Optional {
+		return fmt.Sprintf("%s?.%s", m.Object, m.Property)
+	}
 	return fmt.Sprintf("%s.%s", m.Object, m.Property)
 }
 
 // CallExpression represents function calls.
 // Examples: print("hello"), add(5, 3), obj.method()
 type CallExpression struct {
-	Type   NodeType     `json:"type"`   // Node type (always CALL_EXPRESSION)
-	Callee Expression   `json:"callee"` // Function being called (Identifier or MemberAccess)
-	Args   []Expression `json:"args"`   // Function arguments
+	Type     NodeType     `json:"type"`     // Node type (always CALL_EXPRESSION)
+	Callee   Expression   `json:"callee"`   // Function being called (Identifier or MemberAccess)
+	Args     []Expression `json:"args"`     // Function arguments
+	Optional bool         `json:"optional"` // True for fn?.(), which gives null when the callee is null
+	Token    *lexer.Token `json:"-"`        // Opening parenthesis token for error reporting
 }
 
 func (c *CallExpression) NodeType() NodeType {
@@ -256,15 +356,37 @@ func (c *CallExpression) NodeType() NodeType {
 }
 
 func (c *CallExpression) String() string {
+	if c.Optional {
+		return fmt.Sprintf("%s?.(%s)", c.Callee, c.Args)
+	}
 	return fmt.Sprintf("%s(%s)", c.Callee, c.Args)
 }
 
+// Parameter is a parameter of a function.
+// Examples: name, greeting = "Hi", ...rest
+type Parameter struct {
+	Name    string     // Parameter name
+	Default Expression // Value used when the argument is missing (nil if the argument is required)
+	Rest    bool       // True for ...name, which collects the remaining arguments in an array
+}
+
+func (p Parameter) String() string {
+	if p.Rest {
+		return "..." + p.Name
+	}
+	if p.Default != nil {
+		return fmt.Sprintf("%s = %s", p.Name, p.Default)
+	}
+	return p.Name
+}
+
 // FunctionDeclaration represents function definitions.
-// Examples: function greet(name) { return "Hello " + name }
+// Examples: function greet(name) { return "Hello " + name }, fun greet(name, greeting = "Hi") { }
 type FunctionDeclaration struct {
-	Identifier string      // Function name
-	Parameters []string    // Parameter names
-	Body       []Statement // Function body statements
+	Identifier string       // Function name
+	Parameters []Parameter  // Function parameters
+	Body       []Statement  // Function body statements
+	Token      *lexer.Token // Function name token for error reporting
 }
 
 func (f *FunctionDeclaration) NodeType() NodeType {
@@ -275,6 +397,55 @@ func (f *FunctionDeclaration) String() string {
 	return fmt.Sprintf("function %s(%s) { %s }", f.Identifier, f.Parameters, f.Body)
 }
 
+// FunctionExpression represents anonymous functions used as values.
+// Arrow functions with an expression body get that expression as their only statement,
+// so it is returned implicitly.
+// Examples: fun (a, b) { return a + b }, (x) => x * 2, x => { println(x) }
+type FunctionExpression struct {
+	Parameters []Parameter  // Function parameters
+	Body       []Statement  // Function body statements
+	Token      *lexer.Token // 'fun' keyword or '=>' token for error reporting
+}
+
+func (f *FunctionExpression) NodeType() NodeType {
+	return NodeTypeFunctionExpression
+}
+
+func (f *FunctionExpression) String() string {
+	return fmt.Sprintf("function (%s) { %s }", f.Parameters, f.Body)
+}
+
+// SpreadElement passes the elements of an array as separate arguments.
+// Examples: max(...numbers), log("values:", ...rest)
+type SpreadElement struct {
+	Argument Expression   // The array being spread
+	Token    *lexer.Token // '...' token for error reporting
+}
+
+func (s *SpreadElement) NodeType() NodeType {
+	return NodeTypeSpreadElement
+}
+
+func (s *SpreadElement) String() string {
+	return fmt.Sprintf("...%s", s.Argument)
+}
+
+// NamedArgument passes an argument to the parameter with the given name.
+// Examples: connect(host: "localhost", port: 80)
+type NamedArgument struct {
+	Name  string       // Parameter name
+	Value Expression   // Argument value
+	Token *lexer.Token // Name token for error reporting
+}
+
+func (n *NamedArgument) NodeType() NodeType {
+	return NodeTypeNamedArgument
+}
+
+func (n *NamedArgument) String() string {
+	return fmt.Sprintf("%s: %s", n.Name, n.Value)
+}
+
 // ElseIfClause represents elseif conditions in if statements.
 // Examples: elseif (age >= 13) { print("Teenager") }
 type ElseIfClause struct {
@@ -307,6 +478,58 @@ func (i *IfStatement) String() string {
 	return fmt.Sprintf("if %s { %s }", i.Condition, i.Body)
 }
 
+// ClassDeclaration represents a user-defined type with fields and methods.
+// Calling the class creates an instance, and methods get the instance as self.
+// Examples: class Point { x, y; fun dot(other) { self.x * other.x + self.y * other.y } },
+//
+//	class Counter { count = 0; fun inc() { self.count++ } }
+type ClassDeclaration struct {
+	Name    string                 // Class name
+	Fields  []ClassField           // Fields in declaration order
+	Methods []*FunctionDeclaration // Methods, init is the constructor
+	Token   *lexer.Token           // Class name token for error reporting
+}
+
+// ClassField is a field of a class with its optional default value.
+type ClassField struct {
+	Name    string     // Field name
+	Default Expression // Evaluated for every new instance (nil means null)
+}
+
+func (c *ClassDeclaration) NodeType() NodeType {
+	return NodeTypeClassDeclaration
+}
+
+func (c *ClassDeclaration) String() string {
+	return fmt.Sprintf("class %s { %v %v }", c.Name, c.Fields, c.Methods)
+}
+
+// MatchExpression compares a value against the patterns of its arms, in order,
+// and evaluates to the body of the first arm that matches.
+// Examples: match n { 1, 2 => "small", n if n > 10 => "big", _ => "medium" }
+type MatchExpression struct {
+	Value Expression   // The value being matched
+	Arms  []MatchArm   // Arms in the order they are tried
+	Token *lexer.Token // 'match' keyword token for error reporting
+}
+
+// MatchArm is one arm of a match expression: pattern, pattern if guard => body.
+// Patterns are expressions restricted to literals, names (which bind the matched value),
+// _ (which matches anything), arrays and objects of patterns.
+type MatchArm struct {
+	Patterns []Expression // Alternative patterns, the arm is taken if any of them matches
+	Guard    Expression   // Condition checked after a pattern matched (nil if there is none)
+	Body     []Statement  // A block, or a single expression whose value is the result
+}
+
+func (m *MatchExpression) NodeType() NodeType {
+	return NodeTypeMatchExpression
+}
+
+func (m *MatchExpression) String() string {
+	return fmt.Sprintf("match %s { %v }", m.Value, m.Arms)
+}
+
 // LoopStatement represents different types of loops.
 // Examples: loop condition { do something }, loop { infinite loop },
 //
@@ -317,12 +540,17 @@ type LoopStatement struct {
 
 	// Range loop fields (nil for condition-based/for-each loops)
 	LoopVar   string     // Loop variable name (e.g., "i" for range, "element" for for-each)
+	Pattern   Expression // Array or object pattern each element is destructured into (for-each only, replaces LoopVar)
+	ValueVar  string     // Second for-each variable (e.g., "value" in loop key, value from obj), "" if not used
 	From      Expression // Start value for range loop OR iterable for for-each loop
 	To        Expression // End value for range loop (nil for for-each)
 	Increment Expression // Optional increment (nil means increment by 1, only for range loops)
 
 	// For-each loop indicator
 	IsForEach bool // True if this is a for-each loop (loop element from arr)
+
+	Label string       // Optional label used by break/continue (outer: loop { })
+	Token *lexer.Token // 'loop' keyword token for error reporting
 }
 
 func (l *LoopStatement) NodeType() NodeType {
@@ -338,8 +566,9 @@ func (l *LoopStatement) String() string {
 }
 
 // BreakExpression represents break statements.
-// Examples: break
+// Examples: break, break outer
 type BreakExpression struct {
+	Label string // Label of the loop to break out of ("" for the innermost loop)
 }
 
 func (b *BreakExpression) NodeType() NodeType {
@@ -347,9 +576,30 @@ func (b *BreakExpression) NodeType() NodeType {
 }
 
 func (b *BreakExpression) String() string {
+	if b.Label != "" {
+		return "break " + b.Label
+	}
 	return "break"
 }
 
+// ContinueExpression represents continue statements.
+// It skips the rest of the loop body and moves on to the next iteration.
+// Examples: continue, continue outer
+type ContinueExpression struct {
+	Label string // Label of the loop to continue ("" for the innermost loop)
+}
+
+func (c *ContinueExpression) NodeType() NodeType {
+	return NodeTypeContinueExpression
+}
+
+func (c *ContinueExpression) String() string {
+	if c.Label != "" {
+		return "continue " + c.Label
+	}
+	return "continue"
+}
+
 // ReturnStatement represents return statements.
 // Examples: return, return value, return x + y
 type ReturnStatement struct {
@@ -367,10 +617,46 @@ func (r *ReturnStatement) String() string {
 	return fmt.Sprintf("return %s", r.Value)
 }
 
+// TryStatement represents error handling with try/catch/finally.
+// Examples: try { risky() } catch err { println(err.message) } finally { cleanup() }
+type TryStatement struct {
+	Body        []Statement // Statements that may fail
+	HasCatch    bool        // True if there is a catch clause
+	CatchVar    string      // Name bound to the error object ("" when omitted)
+	CatchBody   []Statement // Statements to execute when Body fails
+	FinallyBody []Statement // Statements that always run (nil when there is no finally)
+}
+
+func (t *TryStatement) NodeType() NodeType {
+	return NodeTypeTryStatement
+}
+
+func (t *TryStatement) String() string {
+	return fmt.Sprintf("try { %s } catch %s { %s } finally { %s }", t.Body, t.CatchVar, t.CatchBody, t.FinallyBody)
+}
+
+// ThrowStatement represents raising an error from Gloob code.
+// Examples: throw "something went wrong", throw { kind: "Validation", message: "bad input" }
+type ThrowStatement struct {
+	Value Expression   // The value being thrown
+	Token *lexer.Token // 'throw' keyword token for error reporting
+}
+
+func (t *ThrowStatement) NodeType() NodeType {
+	return NodeTypeThrowStatement
+}
+
+func (t *ThrowStatement) String() string {
+	return fmt.Sprintf("throw %s", t.Value)
+}
+
 // ImportStatement represents an import declaration.
-// Example: import "utils/helpers"
+// Examples: import "utils/helpers", import "utils/math" as m, from "utils/math" import add, sub
 type ImportStatement struct {
-	Path string // The path to the file to import
+	Path  string        // The path to the file to import
+	Alias string        // Name bound to the module object by 'as' ("" otherwise)
+	Names []*Identifier // Names picked by 'from ... import' (nil to import every export)
+	Token *lexer.Token  // 'import' or 'from' keyword token for error reporting
 }
 
 func (i *ImportStatement) NodeType() NodeType {
@@ -378,9 +664,34 @@ func (i *ImportStatement) NodeType() NodeType {
 }
 
 func (i *ImportStatement) String() string {
+	if i.Names != nil {
+		names := make([]string, len(i.Names))
+		for index, name := range i.Names {
+			names[index] = name.Name
+		}
+		return fmt.Sprintf("from \"%s\" import %s", i.Path, strings.Join(names, ", "))
+	}
+	if i.Alias != "" {
+		return fmt.Sprintf("import \"%s\" as %s", i.Path, i.Alias)
+	}
 	return fmt.Sprintf("import \"%s\"", i.Path)
 }
 
+// ExportStatement represents a declaration made visible to the files importing the module.
+// Examples: export fun add(a, b) { }, export const PI = 3.14, export class Point { }
+type ExportStatement struct {
+	Declaration Statement    // A VariableDeclaration, FunctionDeclaration or ClassDeclaration
+	Token       *lexer.Token // 'export' keyword token for error reporting
+}
+
+func (e *ExportStatement) NodeType() NodeType {
+	return NodeTypeExportStatement
+}
+
+func (e *ExportStatement) String() string {
+	return fmt.Sprintf("export %s", e.Declaration)
+}
+
 // Array represents array literals.
 // Examples: [1, 2, 3], ["hello", "world"]
 type Array struct {
@@ -395,11 +706,13 @@ func (a *Array) String() string {
 	return fmt.Sprintf("[%v]", a.Elements)
 }
 
-// ArrayIndex represents array element access.
-// Examples: arr[1], arr[i + 1]
+// ArrayIndex represents array element access, and slicing when the index is a range.
+// Examples: arr[1], arr[i + 1], arr[-1], arr[2..4], text[3..]
 type ArrayIndex struct {
-	ArrayExpression Expression // The array expression
-	Index           Expression // The index expression
+	ArrayExpression Expression   // The array expression
+	Index           Expression   // The index expression (a number, a key or a range)
+	Optional        bool         // True for arr?.[index], which gives null when the array is null
+	Token           *lexer.Token // Opening bracket token for error reporting
 }
 
 func (a *ArrayIndex) NodeType() NodeType {
@@ -407,5 +720,48 @@ func (a *ArrayIndex) NodeType() NodeType {
 }
 
 func (a *ArrayIndex) String() string {
+	if a.Optional {
+		return fmt.Sprintf("%s?.[%s]", a.ArrayExpression, a.Index)
+	}
 	return fmt.Sprintf("%s[%s]", a.ArrayExpression, a.Index)
 }
+
+// RangeExpression represents a range of ints, including both ends.
+// Examples: 1..10, 10..1, 0..100:5, 3..
+type RangeExpression struct {
+	Start Expression   // First number of the range
+	End   Expression   // Last number of the range (nil for an open range such as 3..)
+	Step  Expression   // Optional step (nil means 1, or -1 when the range goes down)
+	Token *lexer.Token // '..' token for error reporting
+}
+
+func (r *RangeExpression) NodeType() NodeType {
+	return NodeTypeRangeExpression
+}
+
+func (r *RangeExpression) String() string {
+	result := fmt.Sprintf("%s..", r.Start)
+	if r.End != nil {
+		result += fmt.Sprint(r.End)
+	}
+	if r.Step != nil {
+		result += fmt.Sprintf(":%s", r.Step)
+	}
+	return result
+}
+
+// OptionalChain wraps a chain of member accesses, indexes and calls with at least one optional link.
+// When an optional link finds null, the whole chain evaluates to null.
+// Examples: user?.address.city, list?.[0], callback?.()
+type OptionalChain struct {
+	Type       NodeType   `json:"type"`       // Always NodeTypeOptionalChain
+	Expression Expression `json:"expression"` // The full chain
+}
+
+func (o *OptionalChain) NodeType() NodeType {
+	return NodeTypeOptionalChain
+}
+
+func (o *OptionalChain) String() string {
+	return fmt.Sprintf("%s", o.Expression)
+}
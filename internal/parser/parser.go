@@ -1,10 +1,11 @@
 package parser
 
 import (
-	"fmt"
+	goerrors "errors"
 	"gloob-interpreter/internal/errors"
 	"gloob-interpreter/internal/lexer"
 	"strconv"
+	"strings"
 )
 
 // Parser implements a recursive descent parser for the Gloob language.
@@ -14,6 +15,10 @@ type Parser struct {
 	tokens     []lexer.Token // Current stream of tokens to parse
 	sourceCode string        // Original source code for error reporting
 	filename   string        // Filename for error reporting
+	loops      []string      // Labels of the loops enclosing the current statement ("" for unlabeled loops)
+	functions  int           // Number of function bodies enclosing the current statement
+	blocks     int           // Number of blocks enclosing the current statement (0 at the top level of the file)
+	matchGuard bool          // Whether a match guard is being parsed, where => ends the guard instead of starting an arrow function
 }
 
 // NewParser creates a new parser instance with the given tokens.
@@ -30,26 +35,35 @@ func (p *Parser) at() lexer.Token {
 }
 
 // next consumes and returns the current token, advancing to the next one.
+// The EOF token is never consumed so lookahead is always safe.
 func (p *Parser) next() lexer.Token {
 	token := p.at()
-	p.tokens = p.tokens[1:]
+	if token.Type != lexer.TokenTypeEOF {
+		p.tokens = p.tokens[1:]
+	}
 	return token
 }
 
 // nextWithExpect consumes the current token and expects it to be of a specific type.
-// If the token doesn't match the expected type, it prints an error and exits.
-func (p *Parser) nextWithExpect(expected lexer.TokenType, message string) lexer.Token {
+// If the token doesn't match the expected type, it reports a syntax error.
+func (p *Parser) nextWithExpect(expected lexer.TokenType, kind errors.Kind) lexer.Token {
 	token := p.next()
 	if token.Type != expected {
-		p.syntaxError(token, message)
+		p.syntaxError(token, kind)
 		return lexer.Token{}
 	}
 	return token
 }
 
-// syntaxError prints a detailed syntax error with file context and exits.
-func (p *Parser) syntaxError(token lexer.Token, message string) {
-	errors.SyntaxError(token, p.sourceCode, message)
+// bailout is used to unwind the parser once a syntax error is found.
+// It is recovered in ProduceASTWithFilename and turned into a returned error.
+type bailout struct {
+	err *errors.Error
+}
+
+// syntaxError stops parsing with a detailed syntax error located at token.
+func (p *Parser) syntaxError(token lexer.Token, kind errors.Kind, args ...interface{}) {
+	panic(bailout{err: errors.SyntaxError(token, p.sourceCode, kind, args...)})
 }
 
 // notEOF checks if there are more tokens to parse.
@@ -59,20 +73,36 @@ func (p *Parser) notEOF() bool {
 
 // ProduceAST is the main entry point for parsing.
 // It takes source code, tokenizes it, and produces a complete AST.
-func (p *Parser) ProduceAST(sourceCode string) *Program {
+func (p *Parser) ProduceAST(sourceCode string) (*Program, error) {
 	return p.ProduceASTWithFilename(sourceCode, "<stdin>")
 }
 
 // ProduceASTWithFilename is like ProduceAST but allows specifying a filename for error reporting.
-func (p *Parser) ProduceASTWithFilename(sourceCode string, filename string) *Program {
+// The first syntax error found is returned as an *errors.Error.
+func (p *Parser) ProduceASTWithFilename(sourceCode string, filename string) (program *Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, ok := r.(bailout)
+			if !ok {
+				panic(r)
+			}
+			program, err = nil, b.err
+		}
+	}()
+
 	// Store source code and filename for error reporting
 	p.sourceCode = sourceCode
 	p.filename = filename
+	p.loops = nil
+	p.functions = 0
+	p.blocks = 0
+	p.matchGuard = false
 
 	// First, tokenize the source code
 	p.tokens = lexer.NewLexer(sourceCode, filename).Tokenize()
-	program := &Program{
+	program = &Program{
 		Statements: []Statement{},
+		Filename:   filename,
 	}
 
 	// Parse all statements until EOF
@@ -86,43 +116,124 @@ func (p *Parser) ProduceASTWithFilename(sourceCode string, filename string) *Pro
 		program.Statements = append(program.Statements, statement)
 	}
 
-	return program
+	return program, nil
 }
 
 // parseStatement is the entry point for parsing statements.
 // It determines what type of statement to parse based on the current token.
 func (p *Parser) parseStatement() Statement {
 	switch p.at().Type {
-	case lexer.TokenTypeImport:
+	case lexer.TokenTypeImport, lexer.TokenTypeFrom:
 		return p.parseImportStatement()
+	case lexer.TokenTypeExport:
+		return p.parseExportStatement()
 	case lexer.TokenTypeVar, lexer.TokenTypeConst:
 		return p.parseVariableDeclaration()
 	case lexer.TokenTypeFunction:
+		// fun (x) { } is an anonymous function used as an expression
+		if len(p.tokens) > 1 && p.tokens[1].Type == lexer.TokenTypeOpenParentheses {
+			return p.parseExpression()
+		}
 		return p.parseFunctionDeclaration()
+	case lexer.TokenTypeClass:
+		return p.parseClassDeclaration()
 	case lexer.TokenTypeIf:
 		return p.parseIfStatement()
 	case lexer.TokenTypeLoop:
-		return p.parseLoopStatement()
+		return p.parseLoopStatement("")
 	case lexer.TokenTypeReturn:
 		return p.parseReturnStatement()
+	case lexer.TokenTypeTry:
+		return p.parseTryStatement()
+	case lexer.TokenTypeThrow:
+		return p.parseThrowStatement()
 	case lexer.TokenTypeComment:
 		return p.parseCommentStatement()
+	case lexer.TokenTypeOpenSquareBrackets, lexer.TokenTypeOpenCurlyBrackets:
+		// Destructuring assignment: [a, b] = [b, a]
+		if p.isPatternFollowedBy(lexer.TokenTypeEqual) {
+			return p.parseDestructuringAssignment()
+		}
+		return p.parseExpression()
+	case lexer.TokenTypeIdentifier:
+		// Labeled loop: outer: loop { ... }
+		if len(p.tokens) > 2 && p.tokens[1].Type == lexer.TokenTypeColon && p.tokens[2].Type == lexer.TokenTypeLoop {
+			label := p.next() // consume the label
+			p.next()          // consume colon
+			if p.isEnclosingLoop(label.Literal) {
+				p.syntaxError(label, errors.ErrDuplicateLoopLabel, label.Literal)
+			}
+			return p.parseLoopStatement(label.Literal)
+		}
+		return p.parseExpression()
 	default:
 		// If it's not a statement keyword, treat it as an expression
 		return p.parseExpression()
 	}
 }
 
-// parseImportStatement parses import statements.
-// Examples: import "utils/helpers", import "math.gloob"
+// parseImportStatement parses import statements, which can only appear at the top level.
+// Examples: import "utils/helpers", import "math.gloob" as math, from "utils/math" import add, sub
 func (p *Parser) parseImportStatement() *ImportStatement {
-	p.next() // consume 'import'
+	keyword := p.next() // consume 'import' or 'from'
+	if p.blocks > 0 {
+		p.syntaxError(keyword, errors.ErrNotTopLevel, keyword.Literal)
+	}
 
 	// Expect a string literal with the file path
-	pathToken := p.nextWithExpect(lexer.TokenTypeString, "Expected string path after import")
+	pathToken := p.nextWithExpect(lexer.TokenTypeString, errors.ErrExpectedImportPath)
+	statement := &ImportStatement{
+		Path:  pathToken.Literal,
+		Token: &keyword,
+	}
+
+	if keyword.Type == lexer.TokenTypeFrom {
+		// from "path" import name, other
+		p.nextWithExpect(lexer.TokenTypeImport, errors.ErrExpectedImport)
+		statement.Names = []*Identifier{}
+		for {
+			name := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier)
+			statement.Names = append(statement.Names, &Identifier{Type: NodeTypeIdentifier, Name: name.Literal, Token: &name})
+			if p.at().Type != lexer.TokenTypeComma {
+				break
+			}
+			p.next() // consume ,
+		}
+	} else if p.at().Type == lexer.TokenTypeIdentifier && p.at().Literal == "as" {
+		// import "path" as name
+		p.next() // consume 'as'
+		statement.Alias = p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier).Literal
+	}
+
+	return statement
+}
+
+// parseExportStatement parses a declaration marked with export, which can only appear at the top level.
+// Examples: export fun add(a, b) { return a + b }, export const PI = 3.14, export class Point { x = 0 }
+func (p *Parser) parseExportStatement() *ExportStatement {
+	keyword := p.next() // consume 'export'
+	if p.blocks > 0 {
+		p.syntaxError(keyword, errors.ErrNotTopLevel, keyword.Literal)
+	}
 
-	return &ImportStatement{
-		Path: pathToken.Literal,
+	var declaration Statement
+	switch p.at().Type {
+	case lexer.TokenTypeVar, lexer.TokenTypeConst:
+		declaration = p.parseVariableDeclaration()
+	case lexer.TokenTypeFunction:
+		if len(p.tokens) > 1 && p.tokens[1].Type != lexer.TokenTypeIdentifier {
+			p.syntaxError(keyword, errors.ErrInvalidExport)
+		}
+		declaration = p.parseFunctionDeclaration()
+	case lexer.TokenTypeClass:
+		declaration = p.parseClassDeclaration()
+	default:
+		p.syntaxError(keyword, errors.ErrInvalidExport)
+	}
+
+	return &ExportStatement{
+		Declaration: declaration,
+		Token:       &keyword,
 	}
 }
 func (p *Parser) parseCommentStatement() *Null {
@@ -133,11 +244,29 @@ func (p *Parser) parseCommentStatement() *Null {
 }
 
 // parseVariableDeclaration parses variable and constant declarations.
-// Examples: var name = "value", const PI = 3.14, var x;
+// Examples: var name = "value", const PI = 3.14, var x;, var [a, b] = pair
 func (p *Parser) parseVariableDeclaration() *VariableDeclaration {
 	// Determine if this is a const or var declaration
 	isConstant := p.next().Type == lexer.TokenTypeConst
-	identifier := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier).Literal
+
+	// Destructuring declarations always have a value
+	if p.at().Type == lexer.TokenTypeOpenSquareBrackets || p.at().Type == lexer.TokenTypeOpenCurlyBrackets {
+		patternToken := p.at()
+		pattern := p.parsePattern()
+		p.nextWithExpect(lexer.TokenTypeEqual, errors.ErrExpectedEqual)
+		value := p.parseExpression()
+		if p.at().Type == lexer.TokenTypeSemicolon {
+			p.next()
+		}
+		return &VariableDeclaration{
+			Constant: isConstant,
+			Pattern:  pattern,
+			Value:    value,
+			Token:    &patternToken,
+		}
+	}
+	identifierToken := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier)
+	identifier := identifierToken.Literal
 
 	// Check if this is a declaration without assignment (var x; or var x\n)
 	if p.at().Type == lexer.TokenTypeSemicolon || p.at().Type == lexer.TokenTypeNewline {
@@ -153,6 +282,7 @@ func (p *Parser) parseVariableDeclaration() *VariableDeclaration {
 			Constant:   isConstant,
 			Identifier: identifier,
 			Value:      nil,
+			Token:      &identifierToken,
 		}
 	}
 
@@ -169,6 +299,7 @@ func (p *Parser) parseVariableDeclaration() *VariableDeclaration {
 		Constant:   isConstant,
 		Identifier: identifier,
 		Value:      value,
+		Token:      &identifierToken,
 	}
 }
 
@@ -179,31 +310,108 @@ func (p *Parser) parseExpression() Expression {
 }
 
 // parseAssignmentExpression handles assignment operations with lowest precedence.
-// Examples: name = "value", obj.property = 42
+// Examples: name = "value", obj.property = 42, count += 1
 func (p *Parser) parseAssignmentExpression() Expression {
-	left := p.parseLogicalExpression()
+	left := p.parseNullCoalescingExpression()
 
 	// Check if this is an assignment (right-associative)
-	if p.at().Type == lexer.TokenTypeEqual {
-		p.next()
+	if p.at().Type == lexer.TokenTypeEqual || p.at().Type == lexer.TokenTypeCompoundAssignment {
+		operatorToken := p.next()
 		value := p.parseExpression() // Recursive call for right-associativity
 		return &VariableAssignmentExpression{
 			Identifier: left,
 			Value:      value,
+			Operator:   operatorToken.Literal,
+			Token:      &operatorToken,
 		}
 	}
 
 	return left
 }
 
-// parseLogicalExpression handles logical operators (&& and ||).
-// Examples: a && b, x || y
+// parseDestructuringAssignment parses an assignment to an array or object pattern.
+// Examples: [a, b] = [b, a], { name, age } = user
+func (p *Parser) parseDestructuringAssignment() Expression {
+	pattern := p.parsePattern()
+	operatorToken := p.nextWithExpect(lexer.TokenTypeEqual, errors.ErrExpectedEqual)
+	value := p.parseExpression()
+	return &VariableAssignmentExpression{
+		Identifier: pattern,
+		Value:      value,
+		Operator:   operatorToken.Literal,
+		Token:      &operatorToken,
+	}
+}
+
+// isPatternFollowedBy tells whether the array or object starting at the current token
+// is followed by a token of the given type, as in [a, b] = ... or loop [k, v] from ...
+func (p *Parser) isPatternFollowedBy(tokenType lexer.TokenType) bool {
+	depth := 0
+	for i, token := range p.tokens {
+		switch token.Type {
+		case lexer.TokenTypeOpenSquareBrackets, lexer.TokenTypeOpenCurlyBrackets:
+			depth++
+		case lexer.TokenTypeCloseSquareBrackets, lexer.TokenTypeCloseCurlyBrackets:
+			depth--
+			if depth == 0 {
+				return i+1 < len(p.tokens) && p.tokens[i+1].Type == tokenType
+			}
+		}
+	}
+	return false
+}
+
+// parseNullCoalescingExpression handles the ?? operator, which binds looser than || and &&.
+// Examples: name ?? "anonymous", a ?? b ?? c
+func (p *Parser) parseNullCoalescingExpression() Expression {
+	left := p.parseLogicalExpression()
+
+	for p.at().Type == lexer.TokenTypeNullCoalescing {
+		operatorToken := p.next()
+		right := p.parseLogicalExpression()
+
+		left = &BinaryExpression{
+			Type:     NodeTypeBinaryExpression,
+			Left:     left,
+			Operator: operatorToken.Literal,
+			Right:    right,
+			Token:    &operatorToken,
+		}
+	}
+	return left
+}
+
+// parseLogicalExpression handles the || operator.
+// Examples: x || y, a || b && c
 func (p *Parser) parseLogicalExpression() Expression {
+	left := p.parseLogicalAndExpression()
+
+	// Handle logical operators (left-associative)
+	for p.at().Type == lexer.TokenTypeOr {
+		operatorToken := p.next()
+		operator := operatorToken.Literal
+		right := p.parseLogicalAndExpression()
+
+		left = &BinaryExpression{
+			Type:     NodeTypeBinaryExpression,
+			Left:     left,
+			Operator: operator,
+			Right:    right,
+			Token:    &operatorToken,
+		}
+	}
+	return left
+}
+
+// parseLogicalAndExpression handles the && operator, which binds tighter than ||.
+// Examples: a && b
+func (p *Parser) parseLogicalAndExpression() Expression {
 	left := p.parseComparisonOnlyExpression()
 
 	// Handle logical operators (left-associative)
-	for p.at().Type == lexer.TokenTypeAnd || p.at().Type == lexer.TokenTypeOr {
-		operator := p.next().Literal
+	for p.at().Type == lexer.TokenTypeAnd {
+		operatorToken := p.next()
+		operator := operatorToken.Literal
 		right := p.parseComparisonOnlyExpression()
 
 		left = &BinaryExpression{
@@ -211,6 +419,7 @@ func (p *Parser) parseLogicalExpression() Expression {
 			Left:     left,
 			Operator: operator,
 			Right:    right,
+			Token:    &operatorToken,
 		}
 	}
 	return left
@@ -219,25 +428,59 @@ func (p *Parser) parseLogicalExpression() Expression {
 // parseComparisonOnlyExpression handles comparison operators without logical operators.
 // This is used to prevent infinite recursion in parseComparisonExpression.
 func (p *Parser) parseComparisonOnlyExpression() Expression {
-	left := p.parseAdditiveExpression()
+	left := p.parseRangeExpression()
 
 	// Handle multiple comparison operators (left-associative)
 	for p.at().Type == lexer.TokenTypeEqualEqual || p.at().Type == lexer.TokenTypeNotEqual ||
 		p.at().Type == lexer.TokenTypeGreaterThan || p.at().Type == lexer.TokenTypeGreaterThanEqual ||
-		p.at().Type == lexer.TokenTypeLessThan || p.at().Type == lexer.TokenTypeLessThanEqual {
-		operator := p.next().Literal
-		right := p.parseAdditiveExpression()
+		p.at().Type == lexer.TokenTypeLessThan || p.at().Type == lexer.TokenTypeLessThanEqual ||
+		p.at().Type == lexer.TokenTypeIs {
+		operatorToken := p.next()
+		operator := operatorToken.Literal
+		right := p.parseRangeExpression()
 
 		left = &BinaryExpression{
 			Type:     NodeTypeBinaryExpression,
 			Left:     left,
 			Operator: operator,
 			Right:    right,
+			Token:    &operatorToken,
 		}
 	}
 	return left
 }
 
+// parseRangeExpression handles ranges, which bind looser than arithmetic.
+// The end can be left out when nothing follows, as in arr[3..] or loop n from 1.. { }.
+// Examples: 1..10, 1..n + 1, 0..100:5, 3..
+func (p *Parser) parseRangeExpression() Expression {
+	start := p.parseAdditiveExpression()
+	if p.at().Type != lexer.TokenTypeRange {
+		return start
+	}
+	rangeToken := p.next() // consume ..
+
+	var end, step Expression
+	switch p.at().Type {
+	case lexer.TokenTypeCloseSquareBrackets, lexer.TokenTypeCloseParentheses, lexer.TokenTypeCloseCurlyBrackets,
+		lexer.TokenTypeOpenCurlyBrackets, lexer.TokenTypeComma, lexer.TokenTypeNewline, lexer.TokenTypeSemicolon, lexer.TokenTypeColon, lexer.TokenTypeEOF:
+		// Open range
+	default:
+		end = p.parseAdditiveExpression()
+	}
+	if p.at().Type == lexer.TokenTypeColon {
+		p.next() // consume :
+		step = p.parseAdditiveExpression()
+	}
+
+	return &RangeExpression{
+		Start: start,
+		End:   end,
+		Step:  step,
+		Token: &rangeToken,
+	}
+}
+
 // parseAdditiveExpression handles addition and subtraction operators.
 // Examples: a + b, x - y, "hello" + "world"
 func (p *Parser) parseAdditiveExpression() Expression {
@@ -245,7 +488,8 @@ func (p *Parser) parseAdditiveExpression() Expression {
 
 	// Handle multiple additive operators (left-associative)
 	for p.at().Literal == "+" || p.at().Literal == "-" {
-		operator := p.next().Literal
+		operatorToken := p.next()
+		operator := operatorToken.Literal
 		right := p.parseMultiplicativeExpression()
 
 		left = &BinaryExpression{
@@ -253,6 +497,7 @@ func (p *Parser) parseAdditiveExpression() Expression {
 			Left:     left,
 			Operator: operator,
 			Right:    right,
+			Token:    &operatorToken,
 		}
 	}
 	return left
@@ -261,23 +506,66 @@ func (p *Parser) parseAdditiveExpression() Expression {
 // parseMultiplicativeExpression handles multiplication, division, and modulo operators.
 // Examples: a * b, x / y, n % 2
 func (p *Parser) parseMultiplicativeExpression() Expression {
-	left := p.parsePrimaryExpression()
+	left := p.parseUnaryExpression()
 
 	// Handle multiple multiplicative operators (left-associative)
 	for p.at().Literal == "/" || p.at().Literal == "*" || p.at().Literal == "%" {
-		operator := p.next().Literal
-		right := p.parsePrimaryExpression()
+		operatorToken := p.next()
+		operator := operatorToken.Literal
+		right := p.parseUnaryExpression()
 
 		left = &BinaryExpression{
 			Type:     NodeTypeBinaryExpression,
 			Left:     left,
 			Operator: operator,
 			Right:    right,
+			Token:    &operatorToken,
 		}
 	}
 	return left
 }
 
+// parseUnaryExpression handles prefix operators, which bind tighter than any binary operator,
+// and the postfix increment and decrement operators.
+// Examples: !done, -x, -(a + b), !!value, ++count, count--
+func (p *Parser) parseUnaryExpression() Expression {
+	if p.at().Type == lexer.TokenTypeExclamation || (p.at().Type == lexer.TokenTypeOperator && p.at().Literal == "-") {
+		operatorToken := p.next()
+		return &UnaryExpression{
+			Type:     NodeTypeUnaryExpression,
+			Operator: operatorToken.Literal,
+			Operand:  p.parseUnaryExpression(),
+			Token:    &operatorToken,
+		}
+	}
+
+	// Prefix increment and decrement: ++x, --x
+	if p.at().Type == lexer.TokenTypeIncrement {
+		operatorToken := p.next()
+		return &UpdateExpression{
+			Type:     NodeTypeUpdateExpression,
+			Operator: operatorToken.Literal,
+			Target:   p.parseUnaryExpression(),
+			Prefix:   true,
+			Token:    &operatorToken,
+		}
+	}
+
+	expr := p.parsePrimaryExpression()
+
+	// Postfix increment and decrement: x++, x--
+	if p.at().Type == lexer.TokenTypeIncrement {
+		operatorToken := p.next()
+		return &UpdateExpression{
+			Type:     NodeTypeUpdateExpression,
+			Operator: operatorToken.Literal,
+			Target:   expr,
+			Token:    &operatorToken,
+		}
+	}
+	return expr
+}
+
 // parsePrimaryExpression handles the highest precedence expressions.
 // These include literals, identifiers, parentheses, and object literals.
 func (p *Parser) parsePrimaryExpression() Expression {
@@ -286,22 +574,25 @@ func (p *Parser) parsePrimaryExpression() Expression {
 	tokenType := p.at().Type
 	switch tokenType {
 	case lexer.TokenTypeIdentifier:
+		if p.isArrowFunction() {
+			return p.parseArrowFunction()
+		}
 		token := p.next()
 		expr = &Identifier{
 			Type:  NodeTypeIdentifier,
 			Name:  token.Literal,
 			Token: &token,
 		}
-	case lexer.TokenTypeNumber:
-		value, err := strconv.ParseFloat(p.next().Literal, 64)
-		if err != nil {
-			panic(err)
-		}
-		expr = &Numeric{
-			Type:  NodeTypeNumeric,
-			Value: value,
-		}
+	case lexer.TokenTypeInteger, lexer.TokenTypeFloat:
+		expr = p.parseNumber()
+	case lexer.TokenTypeFunction:
+		expr = p.parseFunctionExpression()
 	case lexer.TokenTypeOpenParentheses:
+		if p.isArrowFunction() {
+			// The body of an arrow function extends as far right as possible,
+			// so postfix operations belong to it rather than to the function
+			return p.parseArrowFunction()
+		}
 		p.next()
 		expr = p.parseExpression()
 		p.nextWithExpect(lexer.TokenTypeCloseParentheses, errors.ErrExpectedCloseParen)
@@ -320,15 +611,18 @@ func (p *Parser) parsePrimaryExpression() Expression {
 			Type:  NodeTypeString,
 			Value: token.Literal,
 		}
-	case lexer.TokenTypeBreak:
-		p.next()
-		expr = &BreakExpression{}
+	case lexer.TokenTypeTemplate:
+		expr = p.parseTemplateString()
+	case lexer.TokenTypeBreak, lexer.TokenTypeContinue:
+		expr = p.parseLoopControl()
 	case lexer.TokenTypeOpenCurlyBrackets:
 		expr = p.parseObjectExpression()
 	case lexer.TokenTypeOpenSquareBrackets:
 		expr = p.parseArrayExpression()
+	case lexer.TokenTypeMatch:
+		expr = p.parseMatchExpression()
 	default:
-		p.syntaxError(p.at(), fmt.Sprintf(errors.ErrUnexpectedToken, p.at().Literal))
+		p.syntaxError(p.at(), errors.ErrUnexpectedToken, p.at().Literal)
 		return nil
 	}
 
@@ -336,9 +630,91 @@ func (p *Parser) parsePrimaryExpression() Expression {
 	return p.parsePostfixExpression(expr)
 }
 
+// parseTemplateString splits a template string into its text and its embedded expressions.
+// Examples: `Hello ${name}`, `${a} + ${b} = ${a + b}`
+func (p *Parser) parseTemplateString() Expression {
+	token := p.next()
+	raw := []rune(token.Literal)
+	template := &TemplateString{Parts: []Expression{}, Token: &token}
+
+	// Track the position of every character so embedded expressions report their real position
+	line, column := token.Line, token.ColumnStart+1
+	advance := func(chars []rune) {
+		for _, r := range chars {
+			if r == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+	}
+
+	text := ""
+	addText := func() {
+		if text != "" {
+			template.Parts = append(template.Parts, &String{Type: NodeTypeString, Value: lexer.Unescape(text)})
+			text = ""
+		}
+	}
+
+	for i := 0; i < len(raw); {
+		switch {
+		case raw[i] == '\\' && i+1 < len(raw):
+			// Keep escape sequences (including \$) for Unescape
+			text += string(raw[i : i+2])
+			advance(raw[i : i+2])
+			i += 2
+		case raw[i] == '$' && i+1 < len(raw) && raw[i+1] == '{':
+			addText()
+			advance(raw[i : i+2])
+			end := lexer.EmbeddedExpressionEnd(raw, i+2)
+			source := raw[i+2 : end]
+			template.Parts = append(template.Parts, p.parseEmbeddedExpression(string(source), line, column))
+			advance(raw[i+2 : end+1])
+			i = end + 1
+		default:
+			text += string(raw[i])
+			advance(raw[i : i+1])
+			i++
+		}
+	}
+	addText()
+
+	return template
+}
+
+// parseEmbeddedExpression parses the source of a ${ } expression found at the given position.
+func (p *Parser) parseEmbeddedExpression(source string, line int, column int) Expression {
+	embedded := &Parser{
+		tokens:     lexer.NewLexerAt(source, p.filename, line, column).Tokenize(),
+		sourceCode: p.sourceCode,
+		filename:   p.filename,
+		loops:      p.loops,
+		functions:  p.functions,
+	}
+
+	embedded.skipNewlines()
+	expression := embedded.parseExpression()
+	embedded.skipNewlines()
+	if embedded.notEOF() {
+		embedded.syntaxError(embedded.at(), errors.ErrUnexpectedToken, embedded.at().Literal)
+	}
+	return expression
+}
+
+// skipNewlines consumes newline tokens.
+func (p *Parser) skipNewlines() {
+	for p.at().Type == lexer.TokenTypeNewline {
+		p.next()
+	}
+}
+
 // parsePostfixExpression handles member access, array indexing, and function calls
 // that can be chained after any expression (e.g., "hello".len(), [1,2,3].pop(), etc.)
+// A chain with optional links (user?.name, list?.[1], callback?.()) is wrapped in an OptionalChain.
 func (p *Parser) parsePostfixExpression(expr Expression) Expression {
+	optional := false
 	for {
 		switch p.at().Type {
 		case lexer.TokenTypeOpenSquareBrackets:
@@ -347,7 +723,28 @@ func (p *Parser) parsePostfixExpression(expr Expression) Expression {
 			expr = p.parseCallExpression(expr)
 		case lexer.TokenTypeDot:
 			expr = p.parseMemberAccess(expr)
+		case lexer.TokenTypeOptionalChain:
+			optional = true
+			switch p.tokens[1].Type {
+			case lexer.TokenTypeOpenSquareBrackets:
+				p.next() // consume ?.
+				index := p.parseArrayIndex(expr).(*ArrayIndex)
+				index.Optional = true
+				expr = index
+			case lexer.TokenTypeOpenParentheses:
+				p.next() // consume ?.
+				call := p.parseCallExpression(expr)
+				call.Optional = true
+				expr = call
+			default:
+				member := p.parseMemberAccess(expr).(*MemberAccess)
+				member.Optional = true
+				expr = member
+			}
 		default:
+			if optional {
+				return &OptionalChain{Type: NodeTypeOptionalChain, Expression: expr}
+			}
 			return expr
 		}
 	}
@@ -356,27 +753,153 @@ func (p *Parser) parsePostfixExpression(expr Expression) Expression {
 func (p *Parser) parseFunctionDeclaration() *FunctionDeclaration {
 	p.next()
 	identifier := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedFunctionName)
-	args := p.parseArguments()
-	var params []string
-	for _, arg := range args {
-		if _, ok := arg.(*Identifier); !ok {
-			p.syntaxError(p.at(), errors.ErrExpectedIdentifierParam)
-			return nil
-		}
-		params = append(params, arg.(*Identifier).Name)
-	}
+	params := p.parseParameters()
 
 	p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
-	body := p.parseBlock()
+	body := p.parseFunctionBody()
 	return &FunctionDeclaration{
 		Identifier: identifier.Literal,
 		Parameters: params,
 		Body:       body,
+		Token:      &identifier,
+	}
+
+}
+
+// parseParameters parses the parenthesized parameter list of a function.
+// Parameters can have a default value, and the last one can be a rest parameter.
+// Examples: (), (a), (a, b), (name, greeting = "Hi"), (first, ...rest)
+func (p *Parser) parseParameters() []Parameter {
+	p.nextWithExpect(lexer.TokenTypeOpenParentheses, errors.ErrExpectedOpenParen)
+	params := []Parameter{}
+	for p.notEOF() && p.at().Type != lexer.TokenTypeCloseParentheses {
+		// Skip newlines and commas
+		if p.at().Type == lexer.TokenTypeNewline || p.at().Type == lexer.TokenTypeComma {
+			p.next()
+			continue
+		}
+		if len(params) > 0 && params[len(params)-1].Rest {
+			p.syntaxError(p.at(), errors.ErrRestParameterNotLast, params[len(params)-1].Name)
+		}
+
+		param := Parameter{}
+		if p.at().Type == lexer.TokenTypeEllipsis {
+			p.next() // consume ...
+			param.Rest = true
+		}
+		param.Name = p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifierParam).Literal
+		if p.at().Type == lexer.TokenTypeEqual {
+			if param.Rest {
+				p.syntaxError(p.at(), errors.ErrRestParameterDefault)
+			}
+			p.next() // consume =
+			param.Default = p.parseExpression()
+		}
+		params = append(params, param)
 	}
+	p.nextWithExpect(lexer.TokenTypeCloseParentheses, errors.ErrExpectedCloseParen)
+	return params
+}
 
+// parseFunctionBody parses the block of a function.
+func (p *Parser) parseFunctionBody() []Statement {
+	return p.insideFunction(p.parseBlock)
+}
+
+// insideFunction runs parse as the body of a function.
+// Loops outside the function are not visible from it, so break and continue cannot cross it.
+func (p *Parser) insideFunction(parse func() []Statement) []Statement {
+	loops := p.loops
+	p.loops = nil
+	p.functions++
+	body := parse()
+	p.functions--
+	p.loops = loops
+	return body
+}
+
+// parseFunctionExpression parses anonymous functions.
+// Examples: fun (a, b) { return a + b }, fun () { println("hi") }
+func (p *Parser) parseFunctionExpression() Expression {
+	token := p.next() // consume 'fun'
+	params := p.parseParameters()
+	p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
+	return &FunctionExpression{
+		Parameters: params,
+		Body:       p.parseFunctionBody(),
+		Token:      &token,
+	}
+}
+
+// isArrowFunction tells whether the tokens ahead start an arrow function:
+// an identifier or a parenthesized list followed by =>.
+func (p *Parser) isArrowFunction() bool {
+	if p.matchGuard {
+		return false
+	}
+	if p.at().Type == lexer.TokenTypeIdentifier {
+		return len(p.tokens) > 1 && p.tokens[1].Type == lexer.TokenTypeArrow
+	}
+
+	// Find the matching closing parenthesis and look right after it
+	depth := 0
+	for i, token := range p.tokens {
+		switch token.Type {
+		case lexer.TokenTypeOpenParentheses:
+			depth++
+		case lexer.TokenTypeCloseParentheses:
+			depth--
+			if depth == 0 {
+				return i+1 < len(p.tokens) && p.tokens[i+1].Type == lexer.TokenTypeArrow
+			}
+		}
+	}
+	return false
+}
+
+// parseArrowFunction parses the short function form. The body is either a block
+// or a single expression whose value is returned.
+// Examples: (x) => x * 2, x => x + 1, (a, b) => { return a + b }
+func (p *Parser) parseArrowFunction() Expression {
+	var params []Parameter
+	if p.at().Type == lexer.TokenTypeIdentifier {
+		params = []Parameter{{Name: p.next().Literal}}
+	} else {
+		params = p.parseParameters()
+	}
+	token := p.nextWithExpect(lexer.TokenTypeArrow, errors.ErrExpectedArrow)
+
+	if p.at().Type == lexer.TokenTypeOpenCurlyBrackets {
+		p.next() // consume the opening brace
+		return &FunctionExpression{
+			Parameters: params,
+			Body:       p.parseFunctionBody(),
+			Token:      &token,
+		}
+	}
+
+	body := p.insideFunction(func() []Statement {
+		return []Statement{p.parseExpression()}
+	})
+	return &FunctionExpression{
+		Parameters: params,
+		Body:       body,
+		Token:      &token,
+	}
+}
+
+// parseLoopBody parses the block of a loop, making the loop a target for break and continue.
+func (p *Parser) parseLoopBody(label string) []Statement {
+	p.loops = append(p.loops, label)
+	body := p.parseBlock()
+	p.loops = p.loops[:len(p.loops)-1]
+	return body
 }
 
 func (p *Parser) parseBlock() []Statement {
+	p.blocks++
+	defer func() { p.blocks-- }()
+
 	statements := []Statement{}
 	for p.notEOF() && p.at().Type != lexer.TokenTypeCloseCurlyBrackets {
 		// Skip newlines
@@ -391,26 +914,6 @@ func (p *Parser) parseBlock() []Statement {
 	return statements
 }
 
-func (p *Parser) parseArguments() []Expression {
-	p.nextWithExpect(lexer.TokenTypeOpenParentheses, errors.ErrExpectedOpenParen)
-	arguments := []Expression{}
-	for p.notEOF() && p.at().Type != lexer.TokenTypeCloseParentheses {
-		// Skip newlines
-		if p.at().Type == lexer.TokenTypeNewline {
-			p.next()
-			continue
-		}
-		if p.at().Type == lexer.TokenTypeComma {
-			p.next()
-			continue
-		}
-		argument := p.parseExpression()
-		arguments = append(arguments, argument)
-	}
-	p.nextWithExpect(lexer.TokenTypeCloseParentheses, errors.ErrExpectedCloseParen)
-	return arguments
-}
-
 // parseObjectExpression parses object literals.
 // Examples: { name: "John", age: 30 }, { }, { nested: { value: 42 } }
 func (p *Parser) parseObjectExpression() Expression {
@@ -482,32 +985,40 @@ func (p *Parser) parseArrayExpression() Expression {
 // parseArrayIndex handles array element access.
 // Examples: arr[1], arr[i + 1]
 func (p *Parser) parseArrayIndex(array Expression) Expression {
-	p.next() // consume the opening bracket
+	token := p.next() // consume the opening bracket
 	index := p.parseExpression()
 	p.nextWithExpect(lexer.TokenTypeCloseSquareBrackets, errors.ErrExpectedCloseSquare)
 
 	return &ArrayIndex{
 		ArrayExpression: array,
 		Index:           index,
+		Token:           &token,
 	}
 }
 
 // parseMemberAccess handles property access.
 // Examples: obj.name, person.address, str.len
 func (p *Parser) parseMemberAccess(object Expression) Expression {
-	p.next() // consume the dot
-	property := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier).Literal
+	p.next() // consume the dot (or ?.)
+	propertyToken := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier)
 
 	return &MemberAccess{
 		Object:   object,
-		Property: property,
+		Property: propertyToken.Literal,
+		Token:    &propertyToken,
 	}
 }
 
 func (p *Parser) parseCallExpression(callee Expression) *CallExpression {
-	p.nextWithExpect(lexer.TokenTypeOpenParentheses, errors.ErrExpectedOpenParen)
+	token := p.nextWithExpect(lexer.TokenTypeOpenParentheses, errors.ErrExpectedOpenParen)
+
+	// Arguments can be arrow functions even inside a match guard: n if list.some(x => x > n) => ...
+	matchGuard := p.matchGuard
+	p.matchGuard = false
+	defer func() { p.matchGuard = matchGuard }()
 
 	args := []Expression{}
+	named := false
 
 	// Parse arguments
 	for p.notEOF() && p.at().Type != lexer.TokenTypeCloseParentheses {
@@ -517,7 +1028,13 @@ func (p *Parser) parseCallExpression(callee Expression) *CallExpression {
 			continue
 		}
 
-		arg := p.parseExpression()
+		argToken := p.at()
+		arg := p.parseArgument()
+		if _, ok := arg.(*NamedArgument); ok {
+			named = true
+		} else if named {
+			p.syntaxError(argToken, errors.ErrPositionalAfterNamed)
+		}
 		args = append(args, arg)
 
 		// Check for comma separator
@@ -532,7 +1049,32 @@ func (p *Parser) parseCallExpression(callee Expression) *CallExpression {
 		Type:   NodeTypeCallExpression,
 		Callee: callee,
 		Args:   args,
+		Token:  &token,
+	}
+}
+
+// parseArgument parses an argument of a call: an expression, a spread array or a named argument.
+// Examples: x + 1, ...numbers, port: 80
+func (p *Parser) parseArgument() Expression {
+	if p.at().Type == lexer.TokenTypeEllipsis {
+		token := p.next() // consume ...
+		return &SpreadElement{
+			Argument: p.parseExpression(),
+			Token:    &token,
+		}
+	}
+
+	if p.at().Type == lexer.TokenTypeIdentifier && p.tokens[1].Type == lexer.TokenTypeColon {
+		token := p.next() // consume the name
+		p.next()          // consume colon
+		return &NamedArgument{
+			Name:  token.Literal,
+			Value: p.parseExpression(),
+			Token: &token,
+		}
 	}
+
+	return p.parseExpression()
 }
 
 func (p *Parser) parseIfStatement() *IfStatement {
@@ -577,29 +1119,258 @@ func (p *Parser) parseIfStatement() *IfStatement {
 	return ifStatement
 }
 
-func (p *Parser) parseLoopStatement() *LoopStatement {
-	p.next() // consume 'loop'
+// parseClassDeclaration parses user-defined types: fields with optional default values, and methods.
+// Members are separated by commas, semicolons or newlines.
+// Examples: class Point { x, y; fun dot(other) { self.x * other.x + self.y * other.y } },
+//
+//	class Counter { count = 0; fun inc() { self.count++ } }
+func (p *Parser) parseClassDeclaration() *ClassDeclaration {
+	p.next() // consume 'class'
+	name := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedClassName)
+	p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
+
+	class := &ClassDeclaration{
+		Name:  name.Literal,
+		Token: &name,
+	}
+	members := map[string]bool{}
+	declare := func(member lexer.Token) {
+		if members[member.Literal] {
+			p.syntaxError(member, errors.ErrDuplicateClassMember, member.Literal, class.Name)
+		}
+		members[member.Literal] = true
+	}
+
+	for p.notEOF() && p.at().Type != lexer.TokenTypeCloseCurlyBrackets {
+		switch p.at().Type {
+		case lexer.TokenTypeNewline, lexer.TokenTypeComma, lexer.TokenTypeSemicolon:
+			p.next()
+		case lexer.TokenTypeComment:
+			p.parseCommentStatement()
+		case lexer.TokenTypeFunction:
+			declare(p.tokens[1])
+			class.Methods = append(class.Methods, p.parseFunctionDeclaration())
+		default:
+			fieldToken := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier)
+			declare(fieldToken)
+			field := ClassField{Name: fieldToken.Literal}
+			if p.at().Type == lexer.TokenTypeEqual {
+				p.next() // consume =
+				field.Default = p.parseExpression()
+			}
+			class.Fields = append(class.Fields, field)
+		}
+	}
+	p.nextWithExpect(lexer.TokenTypeCloseCurlyBrackets, errors.ErrExpectedCloseCurly)
+
+	return class
+}
+
+// parseMatchExpression parses match expressions. Arms are separated by newlines or commas.
+// Examples: match n { 1, 2 => "small", n if n > 10 => "big", _ => "medium" },
+//
+//	match point { [0, 0] => "origin", { x: 0, y } => { println(y) } }
+func (p *Parser) parseMatchExpression() Expression {
+	token := p.next() // consume 'match'
+	value := p.parseExpression()
+	p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
+
+	arms := []MatchArm{}
+	for p.notEOF() && p.at().Type != lexer.TokenTypeCloseCurlyBrackets {
+		// Skip newlines, comments and the commas between arms
+		switch p.at().Type {
+		case lexer.TokenTypeNewline, lexer.TokenTypeComma:
+			p.next()
+			continue
+		case lexer.TokenTypeComment:
+			p.parseCommentStatement()
+			continue
+		}
+		arms = append(arms, p.parseMatchArm())
+	}
+	p.nextWithExpect(lexer.TokenTypeCloseCurlyBrackets, errors.ErrExpectedCloseCurly)
+
+	return &MatchExpression{
+		Value: value,
+		Arms:  arms,
+		Token: &token,
+	}
+}
+
+// parseMatchArm parses one arm of a match expression: pattern, pattern if guard => body.
+// The body is a block, or a single expression whose value is the result.
+func (p *Parser) parseMatchArm() MatchArm {
+	arm := MatchArm{Patterns: []Expression{p.parsePattern()}}
+	for p.at().Type == lexer.TokenTypeComma {
+		p.next() // consume comma
+		p.skipNewlines()
+		arm.Patterns = append(arm.Patterns, p.parsePattern())
+	}
+
+	if p.at().Type == lexer.TokenTypeIf {
+		p.next() // consume 'if'
+		matchGuard := p.matchGuard
+		p.matchGuard = true
+		arm.Guard = p.parseExpression()
+		p.matchGuard = matchGuard
+	}
+	p.nextWithExpect(lexer.TokenTypeArrow, errors.ErrExpectedMatchArrow)
+
+	if p.at().Type == lexer.TokenTypeOpenCurlyBrackets {
+		p.next() // consume the opening brace
+		arm.Body = p.parseBlock()
+	} else {
+		arm.Body = []Statement{p.parseExpression()}
+	}
+	return arm
+}
+
+// parsePattern parses a pattern, used by match arms and destructuring: a literal, a name that binds
+// the value, _ that matches anything, or an array or object of patterns. { age } is short for { age: age },
+// and the last element of an array pattern can be ...rest to collect the remaining elements.
+// Examples: 42, -1, "text", null, _, n, [first, second], [head, ...tail], { name: n, age }
+func (p *Parser) parsePattern() Expression {
+	token := p.at()
+	switch token.Type {
+	case lexer.TokenTypeIdentifier:
+		p.next()
+		return &Identifier{
+			Type:  NodeTypeIdentifier,
+			Name:  token.Literal,
+			Token: &token,
+		}
+	case lexer.TokenTypeInteger, lexer.TokenTypeFloat:
+		return p.parseNumber()
+	case lexer.TokenTypeOperator:
+		// Negative numbers: -1, -0.5
+		if token.Literal != "-" || (p.tokens[1].Type != lexer.TokenTypeInteger && p.tokens[1].Type != lexer.TokenTypeFloat) {
+			p.syntaxError(token, errors.ErrInvalidPattern, token.Literal)
+		}
+		p.next() // consume -
+		number := p.parseNumber()
+		number.Value = -number.Value
+		number.Int = -number.Int
+		return number
+	case lexer.TokenTypeString:
+		p.next()
+		return &String{
+			Type:  NodeTypeString,
+			Value: token.Literal,
+		}
+	case lexer.TokenTypeNull:
+		p.next()
+		return &Null{}
+	case lexer.TokenTypeTrue, lexer.TokenTypeYes, lexer.TokenTypeOn:
+		p.next()
+		return &Boolean{Value: true}
+	case lexer.TokenTypeFalse, lexer.TokenTypeNo, lexer.TokenTypeOff:
+		p.next()
+		return &Boolean{Value: false}
+	case lexer.TokenTypeOpenSquareBrackets:
+		p.next() // consume the opening bracket
+		elements := []Expression{}
+		for p.notEOF() && p.at().Type != lexer.TokenTypeCloseSquareBrackets {
+			if p.at().Type == lexer.TokenTypeNewline || p.at().Type == lexer.TokenTypeComma {
+				p.next()
+				continue
+			}
+			if len(elements) > 0 {
+				if rest, ok := elements[len(elements)-1].(*SpreadElement); ok {
+					p.syntaxError(*rest.Token, errors.ErrRestElementNotLast, rest.Argument)
+				}
+			}
+			if p.at().Type == lexer.TokenTypeEllipsis {
+				token := p.next() // consume ...
+				name := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier)
+				elements = append(elements, &SpreadElement{
+					Argument: &Identifier{Type: NodeTypeIdentifier, Name: name.Literal, Token: &name},
+					Token:    &token,
+				})
+				continue
+			}
+			elements = append(elements, p.parsePattern())
+		}
+		p.nextWithExpect(lexer.TokenTypeCloseSquareBrackets, errors.ErrExpectedCloseSquare)
+		return &Array{Elements: elements}
+	case lexer.TokenTypeOpenCurlyBrackets:
+		p.next() // consume the opening brace
+		properties := []Property{}
+		for p.notEOF() && p.at().Type != lexer.TokenTypeCloseCurlyBrackets {
+			if p.at().Type == lexer.TokenTypeNewline || p.at().Type == lexer.TokenTypeComma {
+				p.next()
+				continue
+			}
+			key := p.nextWithExpect(lexer.TokenTypeIdentifier, errors.ErrExpectedIdentifier)
+			var value Expression = &Identifier{Type: NodeTypeIdentifier, Name: key.Literal, Token: &key}
+			if p.at().Type == lexer.TokenTypeColon {
+				p.next() // consume colon
+				value = p.parsePattern()
+			}
+			properties = append(properties, Property{Key: key.Literal, Value: value})
+		}
+		p.nextWithExpect(lexer.TokenTypeCloseCurlyBrackets, errors.ErrExpectedCloseCurly)
+		return &Object{Properties: properties}
+	default:
+		p.syntaxError(token, errors.ErrInvalidPattern, token.Literal)
+		return nil
+	}
+}
+
+// The label is "" for unlabeled loops.
+func (p *Parser) parseLoopStatement(label string) *LoopStatement {
+	loopToken := p.next() // consume 'loop'
+
+	// For-each loop destructuring every element: loop [key, value] from pairs { }
+	if (p.at().Type == lexer.TokenTypeOpenSquareBrackets || p.at().Type == lexer.TokenTypeOpenCurlyBrackets) &&
+		p.isPatternFollowedBy(lexer.TokenTypeFrom) {
+		pattern := p.parsePattern()
+		p.next() // consume 'from'
+		from := p.parseExpression()
+		p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
+		body := p.parseLoopBody(label)
+
+		return &LoopStatement{
+			Pattern:   pattern,
+			From:      from,
+			IsForEach: true,
+			Body:      body,
+			Token:     &loopToken,
+			Label:     label,
+		}
+	}
 
 	// Check if this is an infinite loop (no condition, directly follows with {)
 	if p.at().Type == lexer.TokenTypeOpenCurlyBrackets {
 		// Infinite loop - no condition
 		p.next() // consume the opening brace
-		body := p.parseBlock()
+		body := p.parseLoopBody(label)
 		return &LoopStatement{
 			Condition: nil,
 			Body:      body,
+			Token:     &loopToken,
+			Label:     label,
 		}
 	}
 
-	// Check if this is a range loop or for-each loop (loop <var> from ...)
-	if p.at().Type == lexer.TokenTypeIdentifier && len(p.tokens) > 4 && p.tokens[1].Type == lexer.TokenTypeFrom {
+	// Check if this is a range loop or for-each loop (loop <var> from ... or loop <key>, <value> from ...)
+	twoVariables := p.at().Type == lexer.TokenTypeIdentifier && len(p.tokens) > 6 &&
+		p.tokens[1].Type == lexer.TokenTypeComma && p.tokens[2].Type == lexer.TokenTypeIdentifier && p.tokens[3].Type == lexer.TokenTypeFrom
+	if twoVariables || p.at().Type == lexer.TokenTypeIdentifier && len(p.tokens) > 4 && p.tokens[1].Type == lexer.TokenTypeFrom {
 		loopVar := p.next().Literal // consume identifier (e.g., "i" or "element")
-		p.nextWithExpect(lexer.TokenTypeFrom, "Expected 'from' after loop variable")
+		valueVar := ""
+		if twoVariables {
+			p.next() // consume comma
+			valueVar = p.next().Literal
+		}
+		p.nextWithExpect(lexer.TokenTypeFrom, errors.ErrExpectedFrom)
 		from := p.parseExpression()
 
 		// Check if this is a range loop (has 'to') or for-each loop (goes directly to {)
 		if p.at().Type == lexer.TokenTypeTo {
 			// Range loop: loop i from X to Y [; increment]
+			if valueVar != "" {
+				p.syntaxError(loopToken, errors.ErrRangeLoopSingleVariable, loopVar, valueVar)
+			}
 			p.next() // consume 'to'
 			to := p.parseExpression()
 
@@ -611,7 +1382,7 @@ func (p *Parser) parseLoopStatement() *LoopStatement {
 			}
 
 			p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
-			body := p.parseBlock()
+			body := p.parseLoopBody(label)
 
 			return &LoopStatement{
 				LoopVar:   loopVar,
@@ -619,17 +1390,22 @@ func (p *Parser) parseLoopStatement() *LoopStatement {
 				To:        to,
 				Increment: increment,
 				Body:      body,
+				Token:     &loopToken,
+				Label:     label,
 			}
 		} else {
-			// For-each loop: loop element from arr { }
+			// For-each loop: loop element from arr { } or loop key, value from obj { }
 			p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
-			body := p.parseBlock()
+			body := p.parseLoopBody(label)
 
 			return &LoopStatement{
 				LoopVar:   loopVar,
-				From:      from, // This is the iterable (array)
+				ValueVar:  valueVar,
+				From:      from, // This is the iterable (array or object)
 				IsForEach: true,
 				Body:      body,
+				Token:     &loopToken,
+				Label:     label,
 			}
 		}
 	}
@@ -637,18 +1413,64 @@ func (p *Parser) parseLoopStatement() *LoopStatement {
 	// Traditional condition-based loop
 	condition := p.parseExpression()
 	p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
-	body := p.parseBlock()
+	body := p.parseLoopBody(label)
 
 	return &LoopStatement{
 		Condition: condition,
 		Body:      body,
+		Token:     &loopToken,
+		Label:     label,
+	}
+}
+
+// parseNumber parses int and float literals, see lexer.scanNumber for the accepted formats.
+// Examples: 42, 1_000_000, 0xFF, 0b1010, 0o755, 3.14, 1e9, 2.5e-3
+func (p *Parser) parseNumber() *Numeric {
+	token := p.next()
+
+	if token.Type == lexer.TokenTypeFloat {
+		value, err := strconv.ParseFloat(token.Literal, 64)
+		if err != nil {
+			p.numberLiteralError(token, err)
+		}
+		return &Numeric{Type: NodeTypeNumeric, Value: value}
 	}
+
+	// Base 0 understands the 0x, 0b and 0o prefixes and underscores between digits,
+	// but it reads a leading zero as octal, so leading zeros of decimal ints are dropped: 010 is ten.
+	// It also accepts an underscore right after the prefix (0x_FF), which isn't between two digits
+	literal := token.Literal
+	if len(literal) > 2 && strings.ContainsRune("xXbBoO", rune(literal[1])) && literal[2] == '_' {
+		p.syntaxError(token, errors.ErrInvalidNumberLiteral, token.Literal)
+	}
+	if len(literal) < 2 || !strings.ContainsRune("xXbBoO", rune(literal[1])) {
+		literal = strings.TrimLeft(literal, "0")
+		if literal == "" || literal[0] < '0' || literal[0] > '9' {
+			literal = "0" + literal
+		}
+	}
+	value, err := strconv.ParseInt(literal, 0, 64)
+	if err != nil {
+		p.numberLiteralError(token, err)
+	}
+	return &Numeric{Type: NodeTypeNumeric, Value: float64(value), Int: value, IsInt: true}
+}
+
+// numberLiteralError reports a number literal that strconv couldn't parse.
+func (p *Parser) numberLiteralError(token lexer.Token, err error) {
+	if goerrors.Is(err, strconv.ErrRange) {
+		p.syntaxError(token, errors.ErrNumberLiteralOutOfRange, token.Literal)
+	}
+	p.syntaxError(token, errors.ErrInvalidNumberLiteral, token.Literal)
 }
 
 // parseReturnStatement parses return statements.
 // Examples: return, return 42, return x + y
 func (p *Parser) parseReturnStatement() *ReturnStatement {
-	p.next() // consume 'return'
+	token := p.next() // consume 'return'
+	if p.functions == 0 {
+		p.syntaxError(token, errors.ErrReturnOutsideFunction)
+	}
 
 	// Check if return has a value or is bare
 	// If the next token is a closing curly brace or newline, it's a bare return
@@ -665,3 +1487,89 @@ func (p *Parser) parseReturnStatement() *ReturnStatement {
 		Value: value,
 	}
 }
+
+// parseLoopControl parses break and continue, with an optional loop label.
+// Examples: break, continue, break outer, continue outer
+func (p *Parser) parseLoopControl() Expression {
+	token := p.next() // consume 'break' or 'continue'
+
+	label := ""
+	if p.at().Type == lexer.TokenTypeIdentifier {
+		label = p.next().Literal
+	}
+
+	if len(p.loops) == 0 {
+		if token.Type == lexer.TokenTypeBreak {
+			p.syntaxError(token, errors.ErrBreakOutsideLoop)
+		}
+		p.syntaxError(token, errors.ErrContinueOutsideLoop)
+	}
+	if label != "" && !p.isEnclosingLoop(label) {
+		p.syntaxError(token, errors.ErrUnknownLoopLabel, label)
+	}
+
+	if token.Type == lexer.TokenTypeBreak {
+		return &BreakExpression{Label: label}
+	}
+	return &ContinueExpression{Label: label}
+}
+
+// isEnclosingLoop tells whether label names one of the loops enclosing the current statement.
+func (p *Parser) isEnclosingLoop(label string) bool {
+	for _, enclosing := range p.loops {
+		if enclosing == label {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTryStatement parses error handling blocks.
+// Examples: try { } catch err { }, try { } finally { }, try { } catch { } finally { }
+func (p *Parser) parseTryStatement() *TryStatement {
+	p.next() // consume 'try'
+
+	p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
+	tryStatement := &TryStatement{
+		Body: p.parseBlock(),
+	}
+
+	// Parse the optional catch clause
+	if p.at().Type == lexer.TokenTypeCatch {
+		p.next() // consume 'catch'
+		tryStatement.HasCatch = true
+
+		// The error variable is optional
+		if p.at().Type == lexer.TokenTypeIdentifier {
+			tryStatement.CatchVar = p.next().Literal
+		}
+
+		p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
+		tryStatement.CatchBody = p.parseBlock()
+	}
+
+	// Parse the optional finally clause
+	if p.at().Type == lexer.TokenTypeFinally {
+		p.next() // consume 'finally'
+		p.nextWithExpect(lexer.TokenTypeOpenCurlyBrackets, errors.ErrExpectedOpenCurly)
+		tryStatement.FinallyBody = p.parseBlock()
+	}
+
+	if !tryStatement.HasCatch && tryStatement.FinallyBody == nil {
+		p.syntaxError(p.at(), errors.ErrExpectedCatchOrFinally)
+		return nil
+	}
+
+	return tryStatement
+}
+
+// parseThrowStatement parses throw statements.
+// Examples: throw "boom", throw { kind: "NotFound", message: "no such user" }
+func (p *Parser) parseThrowStatement() *ThrowStatement {
+	token := p.next() // consume 'throw'
+
+	return &ThrowStatement{
+		Value: p.parseExpression(),
+		Token: &token,
+	}
+}
@@ -0,0 +1,57 @@
+package scope
+
+import "gloob-interpreter/internal/values"
+
+// Modules keeps track of the modules imported by a program, so every file is evaluated only once
+// no matter how many times, or from how many other files, it is imported.
+// All the scopes of a program share the same Modules.
+type Modules struct {
+	exports map[string]*values.ObjectValue // Exports of the modules evaluated so far, by absolute path
+	loading []loadingModule                // Modules being evaluated, from the main program to the innermost import
+}
+
+// loadingModule is a module being evaluated, waiting for the modules it imports.
+type loadingModule struct {
+	key  string // Absolute path identifying the module
+	path string // Path the module was imported with, used in error messages
+}
+
+func newModules() *Modules {
+	return &Modules{
+		exports: make(map[string]*values.ObjectValue),
+	}
+}
+
+// Exports returns the exports of the module at path if it was already evaluated.
+func (m *Modules) Exports(path string) (*values.ObjectValue, bool) {
+	exports, ok := m.exports[path]
+	return exports, ok
+}
+
+// Start marks the module identified by key as being evaluated.
+// If it already is, the module imports itself through other modules: Start returns false and
+// the chain of imports leading back to it, such as [a.gloob b.gloob a.gloob].
+func (m *Modules) Start(key string, path string) ([]string, bool) {
+	for i, module := range m.loading {
+		if module.key == key {
+			var chain []string
+			for _, module := range m.loading[i:] {
+				chain = append(chain, module.path)
+			}
+			return append(chain, path), false
+		}
+	}
+	m.loading = append(m.loading, loadingModule{key: key, path: path})
+	return nil, true
+}
+
+// Finish marks the module identified by key as evaluated, remembering its exports.
+// The exports are nil when the evaluation failed, so a later import tries again.
+func (m *Modules) Finish(key string, exports *values.ObjectValue) {
+	if last := len(m.loading) - 1; last >= 0 && m.loading[last].key == key {
+		m.loading = m.loading[:last]
+	}
+	if exports != nil {
+		m.exports[key] = exports
+	}
+}
@@ -1,7 +1,6 @@
 package scope
 
 import (
-	"fmt"
 	"gloob-interpreter/internal/errors"
 	"gloob-interpreter/internal/lexer"
 	"gloob-interpreter/internal/values"
@@ -11,7 +10,9 @@ type Scope struct {
 	parent     *Scope
 	variables  map[string]values.RuntimeValue
 	constants  map[string]struct{}
-	sourceCode string // Source code for error reporting
+	sourceCode string   // Source code for error reporting
+	modules    *Modules // Modules imported by the program, shared by all of its scopes
+	calls      *int     // Gloob function calls running in the program, shared by all of its scopes
 }
 
 func NewScope(parent *Scope) *Scope {
@@ -20,42 +21,85 @@ func NewScope(parent *Scope) *Scope {
 		variables: make(map[string]values.RuntimeValue),
 		constants: make(map[string]struct{}),
 	}
-	// Inherit source code from parent if available
+	// Inherit source code and modules from parent if available
 	if parent != nil {
 		scope.sourceCode = parent.sourceCode
+		scope.modules = parent.modules
+		scope.calls = parent.calls
+	} else {
+		scope.modules = newModules()
+		scope.calls = new(int)
 	}
 	return scope
 }
 
+// NewModuleScope creates the top level scope of an imported module.
+// It sits on the outermost scope of s, so it sees the built-ins but no variable of s,
+// and shares the modules of s so each one is evaluated once per program.
+func NewModuleScope(s *Scope) *Scope {
+	return NewScope(s.Root())
+}
+
+// Root returns the outermost scope, the one holding the built-ins shared by every module of the program.
+func (s *Scope) Root() *Scope {
+	root := s
+	for root.parent != nil {
+		root = root.parent
+	}
+	return root
+}
+
+// Modules returns the modules imported by the program the scope belongs to.
+func (s *Scope) Modules() *Modules {
+	return s.modules
+}
+
+// EnterCall counts a function call starting in the program.
+// It returns false, without counting it, when limit calls are already running.
+func (s *Scope) EnterCall(limit int) bool {
+	if *s.calls >= limit {
+		return false
+	}
+	*s.calls++
+	return true
+}
+
+// ExitCall counts a function call of the program as finished.
+func (s *Scope) ExitCall() {
+	*s.calls--
+}
+
 // SetSourceCode sets the source code for error reporting
 func (s *Scope) SetSourceCode(sourceCode string) {
 	s.sourceCode = sourceCode
 }
 
-func (s *Scope) Declare(name string, value values.RuntimeValue, isConstant bool) values.RuntimeValue {
+// SourceCode returns the source code used for error reporting
+func (s *Scope) SourceCode() string {
+	return s.sourceCode
+}
+
+func (s *Scope) Declare(name string, value values.RuntimeValue, isConstant bool) (values.RuntimeValue, error) {
 	if _, ok := s.variables[name]; ok {
-		errors.RuntimeError(nil, "", fmt.Sprintf(errors.ErrVariableAlreadyDeclared, name))
-		return nil
+		return nil, errors.RuntimeError(nil, "", errors.ErrVariableAlreadyDeclared, name)
 	}
 	if isConstant {
 		s.constants[name] = struct{}{}
 	}
 	s.variables[name] = value
-	return value
+	return value, nil
 }
 
-func (s *Scope) Assign(name string, value values.RuntimeValue) values.RuntimeValue {
+func (s *Scope) Assign(name string, value values.RuntimeValue) (values.RuntimeValue, error) {
 	scope := s.Resolve(name)
 	if scope == nil {
-		errors.RuntimeError(nil, "", fmt.Sprintf(errors.ErrVariableNotFound, name))
-		return nil
+		return nil, errors.RuntimeError(nil, "", errors.ErrVariableNotFound, name)
 	}
 	if _, ok := scope.constants[name]; ok {
-		errors.RuntimeError(nil, "", fmt.Sprintf(errors.ErrConstantCannotBeAssigned, name))
-		return nil
+		return nil, errors.RuntimeError(nil, "", errors.ErrConstantCannotBeAssigned, name)
 	}
 	scope.variables[name] = value
-	return value
+	return value, nil
 }
 
 func (s *Scope) Resolve(name string) *Scope {
@@ -70,33 +114,21 @@ func (s *Scope) Resolve(name string) *Scope {
 	return nil // Don't error here, let the caller handle it
 }
 
-func (s *Scope) Get(name string) values.RuntimeValue {
-	scope := s.Resolve(name)
-	if scope == nil {
-		errors.RuntimeError(nil, "", fmt.Sprintf(errors.ErrVariableNotFound, name))
-		return nil
-	}
-	value := scope.variables[name]
-	if value == nil {
-		errors.RuntimeError(nil, "", fmt.Sprintf(errors.ErrVariableNotInitialized, name))
-		return nil
-	}
-	return value
+func (s *Scope) Get(name string) (values.RuntimeValue, error) {
+	return s.GetWithToken(name, nil)
 }
 
 // GetWithToken gets a variable value and reports errors with token information
-func (s *Scope) GetWithToken(name string, token *lexer.Token) values.RuntimeValue {
+func (s *Scope) GetWithToken(name string, token *lexer.Token) (values.RuntimeValue, error) {
 	scope := s.Resolve(name)
 	if scope == nil {
-		errors.RuntimeError(token, s.sourceCode, fmt.Sprintf(errors.ErrVariableNotFound, name))
-		return nil
+		return nil, errors.RuntimeError(token, s.sourceCode, errors.ErrVariableNotFound, name)
 	}
 	value := scope.variables[name]
 	if value == nil {
-		errors.RuntimeError(token, s.sourceCode, fmt.Sprintf(errors.ErrVariableNotInitialized, name))
-		return nil
+		return nil, errors.RuntimeError(token, s.sourceCode, errors.ErrVariableNotInitialized, name)
 	}
-	return value
+	return value, nil
 }
 
 func (s *Scope) GetVariables() map[string]values.RuntimeValue {
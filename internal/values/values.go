@@ -1,9 +1,14 @@
 package values
 
 import (
+	"cmp"
 	"fmt"
 	"gloob-interpreter/internal/colors"
 	"gloob-interpreter/internal/parser"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // RuntimeValue is the interface that all runtime values must implement.
@@ -13,19 +18,98 @@ type RuntimeValue interface {
 	NodeType() parser.NodeType
 }
 
+// IsTruthy tells whether a value counts as true in conditions.
+// false, 0, "" and null are falsy; everything else is truthy.
+func IsTruthy(value RuntimeValue) bool {
+	switch v := value.(type) {
+	case *BooleanValue:
+		return v.Value
+	case *NumericValue:
+		return v.Value != 0
+	case *StringValue:
+		return v.Value != ""
+	case *NullValue:
+		return false
+	default:
+		return true // Objects, functions, etc. are truthy
+	}
+}
+
+// Equal checks if two values are equal, the way contains, indexOf and match patterns compare them.
+// Numbers, strings, booleans and null are compared by value; arrays, objects and functions by identity.
+func Equal(a, b RuntimeValue) bool {
+	if a.NodeType() != b.NodeType() {
+		return false
+	}
+
+	switch a.NodeType() {
+	case parser.NodeTypeNumeric:
+		return CompareNumbers(a.(*NumericValue), b.(*NumericValue)) == 0
+	case parser.NodeTypeString:
+		return a.(*StringValue).Value == b.(*StringValue).Value
+	case parser.NodeTypeBoolean:
+		return a.(*BooleanValue).Value == b.(*BooleanValue).Value
+	case parser.NodeTypeNull:
+		return true
+	default:
+		// For complex types, use pointer comparison
+		return a == b
+	}
+}
+
 // NumericValue represents number values at runtime.
+// A number is either an int (exact 64-bit integer) or a float (float64).
+// Value is always set, so code that only needs an approximate value can ignore the difference.
 // Examples: 42, 3.14, -10
 type NumericValue struct {
 	Type  parser.NodeType `json:"type"`  // Always NodeTypeNumeric
-	Value float64         `json:"value"` // The actual numeric value
+	Value float64         `json:"value"` // The numeric value (converted to float64 for ints)
+	Int   int64           `json:"int"`   // The exact value of an int
+	IsInt bool            `json:"isInt"` // Whether the number is an int or a float
+}
+
+// NewInt creates an int value.
+func NewInt(value int64) *NumericValue {
+	return &NumericValue{Type: parser.NodeTypeNumeric, Value: float64(value), Int: value, IsInt: true}
+}
+
+// NewFloat creates a float value.
+func NewFloat(value float64) *NumericValue {
+	return &NumericValue{Type: parser.NodeTypeNumeric, Value: value}
 }
 
 func (n *NumericValue) NodeType() parser.NodeType {
 	return parser.NodeTypeNumeric
 }
 
+// TypeName returns "int" or "float".
+func (n *NumericValue) TypeName() string {
+	if n.IsInt {
+		return "int"
+	}
+	return "float"
+}
+
+// String formats ints as they are and floats always with a decimal point or an exponent,
+// so 2 and 2.0 can be told apart when printed.
 func (n *NumericValue) String() string {
-	return fmt.Sprintf("%g", n.Value)
+	if n.IsInt {
+		return strconv.FormatInt(n.Int, 10)
+	}
+	text := fmt.Sprintf("%g", n.Value)
+	if !strings.ContainsAny(text, ".eIN") {
+		text += ".0"
+	}
+	return text
+}
+
+// CompareNumbers returns -1, 0 or 1 when a is less than, equal to or greater than b.
+// Two ints are compared exactly; as soon as one of them is a float both are compared as floats.
+func CompareNumbers(a, b *NumericValue) int {
+	if a.IsInt && b.IsInt {
+		return cmp.Compare(a.Int, b.Int)
+	}
+	return cmp.Compare(a.Value, b.Value)
 }
 
 // BooleanValue represents boolean values at runtime.
@@ -90,16 +174,97 @@ func (n *NodeVariableDeclaration) String() string {
 
 // ObjectValue represents object values at runtime.
 // Objects are collections of key-value pairs where keys are strings and values are RuntimeValues.
+// Keys keep the order in which they were added, which is the order used to print and iterate.
 // Examples: { name: "John", age: 30 }, { nested: { value: 42 } }
 type ObjectValue struct {
 	Type       parser.NodeType         `json:"type"`       // Always NodeTypeObject
 	Properties map[string]RuntimeValue `json:"properties"` // Key-value pairs
+	Class      *ClassValue             `json:"-"`          // Class of an instance, nil for plain objects
+	keys       []string                // Keys in insertion order
+}
+
+// NewObjectValue creates an empty object.
+func NewObjectValue() *ObjectValue {
+	return &ObjectValue{
+		Type:       parser.NodeTypeObject,
+		Properties: make(map[string]RuntimeValue),
+	}
 }
 
 func (o *ObjectValue) NodeType() parser.NodeType {
 	return parser.NodeTypeObject
 }
 
+// Get returns the value of a property and whether it exists.
+func (o *ObjectValue) Get(key string) (RuntimeValue, bool) {
+	value, exists := o.Properties[key]
+	return value, exists
+}
+
+// Has checks if the object has a property.
+func (o *ObjectValue) Has(key string) bool {
+	_, exists := o.Properties[key]
+	return exists
+}
+
+// Set adds or replaces a property. New keys go after the existing ones.
+func (o *ObjectValue) Set(key string, value RuntimeValue) {
+	if o.Properties == nil {
+		o.Properties = make(map[string]RuntimeValue)
+	}
+	if _, exists := o.Properties[key]; !exists {
+		o.keys = append(o.Keys(), key)
+	}
+	o.Properties[key] = value
+}
+
+// Delete removes a property and reports whether it existed.
+func (o *ObjectValue) Delete(key string) bool {
+	if _, exists := o.Properties[key]; !exists {
+		return false
+	}
+	keys := o.Keys()
+	for i, k := range keys {
+		if k == key {
+			o.keys = append(keys[:i:i], keys[i+1:]...)
+			break
+		}
+	}
+	delete(o.Properties, key)
+	return true
+}
+
+// Keys returns the keys of the object in insertion order.
+// Properties added to the map directly are kept too, after the known keys and sorted.
+func (o *ObjectValue) Keys() []string {
+	if len(o.keys) == len(o.Properties) {
+		return o.keys
+	}
+
+	known := make(map[string]bool, len(o.keys))
+	keys := make([]string, 0, len(o.Properties))
+	for _, key := range o.keys {
+		if _, exists := o.Properties[key]; exists && !known[key] {
+			known[key] = true
+			keys = append(keys, key)
+		}
+	}
+	var missing []string
+	for key := range o.Properties {
+		if !known[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	o.keys = append(keys, missing...)
+	return o.keys
+}
+
+// Len returns the number of properties.
+func (o *ObjectValue) Len() int {
+	return len(o.Properties)
+}
+
 func (o *ObjectValue) String() string {
 	return "\n" + o.stringWithIndent(0)
 }
@@ -113,8 +278,12 @@ func (o *ObjectValue) stringWithIndent(indentLevel int) string {
 	}
 
 	result := colors.White("{\n")
+	if o.Class != nil {
+		result = colors.White(o.Class.Name + " {\n")
+	}
 	first := true
-	for key, value := range o.Properties {
+	for _, key := range o.Keys() {
+		value := o.Properties[key]
 		if !first {
 			result += ",\n"
 		}
@@ -155,9 +324,10 @@ func (o *ObjectValue) stringWithIndent(indentLevel int) string {
 type FunctionValue struct {
 	Type       parser.NodeType    `json:"type"`       // Always NodeTypeFunctionDeclaration
 	Identifier string             `json:"identifier"` // Function name
-	Parameters []string           `json:"parameters"` // Parameter names
+	Parameters []parser.Parameter `json:"parameters"` // Function parameters
 	Body       []parser.Statement `json:"body"`       // Function body statements
 	Scope      interface{}        `json:"scope"`      // Closure scope (captured variables) - will be set to *scope.Scope
+	Source     string             `json:"-"`          // Source code the function was written in, for error reporting
 }
 
 func (f *FunctionValue) NodeType() parser.NodeType {
@@ -168,6 +338,26 @@ func (f *FunctionValue) String() string {
 	return fmt.Sprintf("function %s(%s) { %s }", f.Identifier, f.Parameters, f.Body)
 }
 
+// ClassValue represents a user-defined type at runtime.
+// Calling a class creates an instance: an object that remembers its class.
+// Examples: class Point { x, y }, Point(1, 2)
+type ClassValue struct {
+	Type    parser.NodeType           `json:"type"`    // Always NodeTypeClass
+	Name    string                    `json:"name"`    // Class name, also returned by type() for its instances
+	Fields  []parser.ClassField       `json:"fields"`  // Fields in declaration order, with their default values
+	Methods map[string]*FunctionValue `json:"methods"` // Methods by name, they get the instance as self
+	Scope   interface{}               `json:"scope"`   // Scope where the class was declared - will be set to *scope.Scope
+	Source  string                    `json:"-"`       // Source code the class was written in, for error reporting
+}
+
+func (c *ClassValue) NodeType() parser.NodeType {
+	return parser.NodeTypeClass
+}
+
+func (c *ClassValue) String() string {
+	return "class " + c.Name
+}
+
 // CollectionValue represents array/collection values at runtime.
 // This is currently not implemented but reserved for future array support.
 type CollectionValue struct {
@@ -186,7 +376,8 @@ func (c *CollectionValue) String() string {
 // BreakValue is a special runtime value that signals a break statement.
 // This is used internally by the interpreter to exit loops.
 type BreakValue struct {
-	Type parser.NodeType `json:"type"` // Always NodeTypeBreakExpression
+	Type  parser.NodeType `json:"type"`  // Always NodeTypeBreakExpression
+	Label string          `json:"label"` // Label of the target loop ("" for the innermost loop)
 }
 
 func (b *BreakValue) NodeType() parser.NodeType {
@@ -194,9 +385,30 @@ func (b *BreakValue) NodeType() parser.NodeType {
 }
 
 func (b *BreakValue) String() string {
+	if b.Label != "" {
+		return "break " + b.Label
+	}
 	return "break"
 }
 
+// ContinueValue is a special runtime value that signals a continue statement.
+// This is used internally by the interpreter to skip to the next loop iteration.
+type ContinueValue struct {
+	Type  parser.NodeType `json:"type"`  // Always NodeTypeContinueExpression
+	Label string          `json:"label"` // Label of the target loop ("" for the innermost loop)
+}
+
+func (c *ContinueValue) NodeType() parser.NodeType {
+	return parser.NodeTypeContinueExpression
+}
+
+func (c *ContinueValue) String() string {
+	if c.Label != "" {
+		return "continue " + c.Label
+	}
+	return "continue"
+}
+
 // ReturnValue is a special value that signals a return from a function.
 // It wraps the actual return value.
 type ReturnValue struct {
@@ -230,12 +442,68 @@ func (a *ArrayValue) String() string {
 	return fmt.Sprintf("%v", a.Elements)
 }
 
+// RangeValue represents a range of ints at runtime, including both ends.
+// Ranges are lazy: their numbers are computed while iterating, so open ranges (3..) are fine.
+// Examples: 1..10, 10..1, 0..100:5, 3..
+type RangeValue struct {
+	Type  parser.NodeType `json:"type"`  // Always NodeTypeRange
+	Start int64           `json:"start"` // First number
+	End   int64           `json:"end"`   // Last possible number (ignored for open ranges)
+	Step  int64           `json:"step"`  // Never 0, negative when the range goes down
+	Open  bool            `json:"open"`  // Whether the range has no end
+}
+
+func (r *RangeValue) NodeType() parser.NodeType {
+	return parser.NodeTypeRange
+}
+
+// DefaultRangeStep is the step of a range written without one: 1, or -1 when it goes down as in 10..1.
+func DefaultRangeStep(start int64, end int64, open bool) int64 {
+	if !open && start > end {
+		return -1
+	}
+	return 1
+}
+
+// At returns the number at the 0-based position i and whether the range is that long.
+// A range ends where its numbers would no longer fit in an int, so open ranges end at the int limits too.
+func (r *RangeValue) At(i int64) (int64, bool) {
+	offset := i * r.Step
+	if i != 0 && (offset/i != r.Step || i == -1 && r.Step == math.MinInt64) {
+		return 0, false
+	}
+	number := r.Start + offset
+	if (number > r.Start) != (offset > 0) && offset != 0 {
+		return 0, false
+	}
+	switch {
+	case r.Open:
+		return number, true
+	case r.Step > 0:
+		return number, number <= r.End
+	default:
+		return number, number >= r.End
+	}
+}
+
+func (r *RangeValue) String() string {
+	result := fmt.Sprintf("%d..", r.Start)
+	if !r.Open {
+		result += fmt.Sprint(r.End)
+	}
+	if r.Step != DefaultRangeStep(r.Start, r.End, r.Open) {
+		result += fmt.Sprintf(":%d", r.Step)
+	}
+	return result
+}
+
 // NativeFunctionValue represents built-in functions at runtime.
 // These are functions implemented in Go that are available globally.
+// A native function reports failures through its error result instead of exiting.
 // Examples: print(), type(), len(), input()
 type NativeFunctionValue struct {
-	Type       parser.NodeType                                           `json:"type"` // Always NodeTypeNativeFunction
-	Expression func(args []RuntimeValue, scope interface{}) RuntimeValue // The Go function to call
+	Type       parser.NodeType                                                    `json:"type"` // Always NodeTypeNativeFunction
+	Expression func(args []RuntimeValue, scope interface{}) (RuntimeValue, error) // The Go function to call
 }
 
 func (n *NativeFunctionValue) NodeType() parser.NodeType {
@@ -0,0 +1,300 @@
+package gloob
+
+import (
+	"fmt"
+	"gloob-interpreter/internal/errors"
+	"gloob-interpreter/internal/interpreter"
+	"gloob-interpreter/internal/parser"
+	"gloob-interpreter/internal/values"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Function is a Gloob function (user-defined or native) handed to Go code.
+// It stays bound to the Interpreter it comes from.
+type Function struct {
+	interpreter *Interpreter
+	value       values.RuntimeValue
+}
+
+// Call calls the function with the given arguments.
+func (f *Function) Call(args ...any) (any, error) {
+	return f.interpreter.call(f.value, args)
+}
+
+// String returns the Gloob representation of the function.
+func (f *Function) String() string {
+	return fmt.Sprint(f.value)
+}
+
+// Range is a Gloob range handed to Go code, such as 1..10:3.
+// Ranges can be open (1..) or very long, so they aren't converted into slices.
+type Range struct {
+	Start int64 // First number
+	End   int64 // Last number, unless the range is open
+	Step  int64 // Difference between two numbers, negative when the range goes down
+	Open  bool  // Whether the range never ends
+}
+
+// goContainer identifies a Go slice or map being converted, to detect values containing themselves.
+type goContainer struct {
+	pointer uintptr
+	length  int
+}
+
+// enter marks container as being converted, or reports a conversion error if it already is,
+// which means the value contains itself. The returned function marks it as converted.
+func enter(seen map[any]bool, container any, description string) (func(), error) {
+	if seen[container] {
+		return nil, errors.RuntimeError(nil, "", errors.ErrCannotConvert, description+" that contains itself")
+	}
+	seen[container] = true
+	return func() { delete(seen, container) }, nil
+}
+
+// conversionError reports a value that can't be converted.
+func conversionError(format string, args ...any) error {
+	return errors.RuntimeError(nil, "", errors.ErrCannotConvert, fmt.Sprintf(format, args...))
+}
+
+// ToValue converts a Go value into a Gloob runtime value.
+//
+//	nil                          -> null
+//	bool                         -> boolean
+//	int, uint and friends        -> int (uints past the int64 range become floats)
+//	float32, float64             -> float
+//	string                       -> string
+//	[]any and other slices       -> array
+//	map[string]any and other maps with string keys -> object
+//	Func, func(...any) (any, error), *Function     -> function
+//	Range                        -> range
+//
+// Any other type, and slices or maps containing themselves, are reported as an *Error.
+func (i *Interpreter) ToValue(value any) (values.RuntimeValue, error) {
+	converted, err := i.toValue(value, make(map[any]bool))
+	if err != nil {
+		return nil, newError(err)
+	}
+	return converted, nil
+}
+
+// toValue converts a Go value, seen holds the slices and maps being converted.
+func (i *Interpreter) toValue(value any, seen map[any]bool) (values.RuntimeValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return &values.NullValue{Type: parser.NodeTypeNull}, nil
+	case bool:
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: v}, nil
+	case string:
+		return &values.StringValue{Type: parser.NodeTypeString, Value: v}, nil
+	case float64:
+		return values.NewFloat(v), nil
+	case int:
+		return values.NewInt(int64(v)), nil
+	case *Function:
+		return v.value, nil
+	case Range:
+		if v.Step == 0 {
+			return nil, conversionError("the range %d..%d with a step of 0", v.Start, v.End)
+		}
+		return &values.RangeValue{Type: parser.NodeTypeRange, Start: v.Start, End: v.End, Step: v.Step, Open: v.Open}, nil
+	case Func:
+		return &values.NativeFunctionValue{Type: parser.NodeTypeNativeFunction, Expression: i.native(v)}, nil
+	case func(args ...any) (any, error):
+		return &values.NativeFunctionValue{Type: parser.NodeTypeNativeFunction, Expression: i.native(v)}, nil
+	}
+
+	// Fall back to reflection for the remaining numbers, slices and maps
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return values.NewInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return values.NewFloat(float64(rv.Uint())), nil
+		}
+		return values.NewInt(int64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return values.NewFloat(rv.Float()), nil
+	case reflect.Bool:
+		return &values.BooleanValue{Type: parser.NodeTypeBoolean, Value: rv.Bool()}, nil
+	case reflect.String:
+		return &values.StringValue{Type: parser.NodeTypeString, Value: rv.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Len() > 0 {
+			exit, err := enter(seen, goContainer{rv.Pointer(), rv.Len()}, fmt.Sprintf("a Go %T", value))
+			if err != nil {
+				return nil, err
+			}
+			defer exit()
+		}
+		return i.toArray(rv.Len(), func(index int) any { return rv.Index(index).Interface() }, seen)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		exit, err := enter(seen, goContainer{rv.Pointer(), -1}, fmt.Sprintf("a Go %T", value))
+		if err != nil {
+			return nil, err
+		}
+		defer exit()
+
+		// Go maps have no order, so their keys are sorted
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+
+		object := values.NewObjectValue()
+		for _, key := range keys {
+			converted, err := i.toValue(rv.MapIndex(key).Interface(), seen)
+			if err != nil {
+				return nil, err
+			}
+			object.Set(key.String(), converted)
+		}
+		return object, nil
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return &values.NullValue{Type: parser.NodeTypeNull}, nil
+		}
+		return i.toValue(rv.Elem().Interface(), seen)
+	}
+
+	return nil, conversionError("Go value of type %T", value)
+}
+
+// FromValue converts a Gloob runtime value into a Go value.
+//
+//	null     -> nil
+//	boolean  -> bool
+//	int      -> int64
+//	float    -> float64
+//	string   -> string
+//	array    -> []any
+//	object   -> map[string]any
+//	range    -> Range
+//	function -> *Function (classes too, calling them creates an instance)
+//
+// Arrays and objects containing themselves are reported as an *Error.
+func (i *Interpreter) FromValue(value values.RuntimeValue) (any, error) {
+	converted, err := i.fromValue(value, make(map[any]bool))
+	if err != nil {
+		return nil, newError(err)
+	}
+	return converted, nil
+}
+
+// fromValue converts a Gloob value, seen holds the arrays and objects being converted.
+func (i *Interpreter) fromValue(value values.RuntimeValue, seen map[any]bool) (any, error) {
+	switch v := value.(type) {
+	case nil, *values.NullValue, *values.BreakValue:
+		return nil, nil
+	case *values.BooleanValue:
+		return v.Value, nil
+	case *values.NumericValue:
+		if v.IsInt {
+			return v.Int, nil
+		}
+		return v.Value, nil
+	case *values.StringValue:
+		return v.Value, nil
+	case *values.RangeValue:
+		return Range{Start: v.Start, End: v.End, Step: v.Step, Open: v.Open}, nil
+	case *values.ArrayValue:
+		exit, err := enter(seen, v, "an array")
+		if err != nil {
+			return nil, err
+		}
+		defer exit()
+
+		array := make([]any, len(v.Elements))
+		for index, element := range v.Elements {
+			converted, err := i.fromValue(element, seen)
+			if err != nil {
+				return nil, err
+			}
+			array[index] = converted
+		}
+		return array, nil
+	case *values.ObjectValue:
+		exit, err := enter(seen, v, "an object")
+		if err != nil {
+			return nil, err
+		}
+		defer exit()
+
+		object := make(map[string]any, v.Len())
+		for _, key := range v.Keys() {
+			converted, err := i.fromValue(v.Properties[key], seen)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = converted
+		}
+		return object, nil
+	case *values.FunctionValue, *values.NativeFunctionValue, *values.ClassValue:
+		return &Function{interpreter: i, value: v}, nil
+	case *values.NodeVariableDeclaration:
+		return i.fromValue(v.Value, seen)
+	case *values.ReturnValue:
+		return i.fromValue(v.Value, seen)
+	}
+
+	return nil, conversionError("Gloob value of type %s", value.NodeType())
+}
+
+// call converts the arguments, calls function and converts its result back.
+func (i *Interpreter) call(function values.RuntimeValue, args []any) (any, error) {
+	runtimeArgs := make([]values.RuntimeValue, len(args))
+	for index, arg := range args {
+		converted, err := i.ToValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		runtimeArgs[index] = converted
+	}
+
+	result, err := interpreter.CallFunction(function, runtimeArgs, i.scope)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return i.FromValue(result)
+}
+
+// native wraps a Go function so it can be stored in a NativeFunctionValue.
+func (i *Interpreter) native(fn Func) func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+	return func(args []values.RuntimeValue, scope interface{}) (values.RuntimeValue, error) {
+		goArgs := make([]any, len(args))
+		for index, arg := range args {
+			converted, err := i.FromValue(arg)
+			if err != nil {
+				return nil, hostError(err)
+			}
+			goArgs[index] = converted
+		}
+
+		result, err := fn(goArgs...)
+		if err != nil {
+			return nil, hostError(err)
+		}
+
+		value, err := i.ToValue(result)
+		if err != nil {
+			return nil, hostError(err)
+		}
+		return value, nil
+	}
+}
+
+// toArray builds a Gloob array out of n Go values returned by at.
+func (i *Interpreter) toArray(n int, at func(index int) any, seen map[any]bool) (values.RuntimeValue, error) {
+	array := &values.ArrayValue{Type: parser.NodeTypeArray, Elements: make([]values.RuntimeValue, n)}
+	for index := 0; index < n; index++ {
+		converted, err := i.toValue(at(index), seen)
+		if err != nil {
+			return nil, err
+		}
+		array.Elements[index] = converted
+	}
+	return array, nil
+}
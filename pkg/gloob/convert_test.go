@@ -0,0 +1,182 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"gloob-interpreter/internal/values"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{"nil", nil, nil},
+		{"bool", true, true},
+		{"string", "héllo", "héllo"},
+		{"int", 42, int64(42)},
+		{"int8", int8(-8), int64(-8)},
+		{"int64", int64(math.MaxInt64), int64(math.MaxInt64)},
+		{"uint", uint(7), int64(7)},
+		{"uint past int64", uint64(math.MaxUint64), float64(math.MaxUint64)},
+		{"float64", 2.5, 2.5},
+		{"whole float64", 2.0, 2.0},
+		{"float32", float32(0.5), 0.5},
+		{"slice", []any{1, "a", nil}, []any{int64(1), "a", nil}},
+		{"typed slice", []int{1, 2}, []any{int64(1), int64(2)}},
+		{"map", map[string]any{"b": 1, "a": []any{2.5}}, map[string]any{"b": int64(1), "a": []any{2.5}}},
+		{"typed map", map[string]bool{"ok": true}, map[string]any{"ok": true}},
+		{"nil pointer", (*int)(nil), nil},
+		{"range", Range{Start: 1, End: 10, Step: 3}, Range{Start: 1, End: 10, Step: 3}},
+		{"open range", Range{Start: 5, Step: -1, Open: true}, Range{Start: 5, Step: -1, Open: true}},
+	}
+
+	vm := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := vm.ToValue(test.in)
+			if err != nil {
+				t.Fatalf("ToValue(%#v) failed: %v", test.in, err)
+			}
+			got, err := vm.FromValue(value)
+			if err != nil {
+				t.Fatalf("FromValue(%v) failed: %v", value, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("round trip of %#v = %#v, want %#v", test.in, got, test.want)
+			}
+		})
+	}
+}
+
+func TestToValueNumbers(t *testing.T) {
+	tests := []struct {
+		in    any
+		isInt bool
+	}{
+		{1, true},
+		{int64(1), true},
+		{uint16(1), true},
+		{1.0, false},
+		{float32(1), false},
+	}
+
+	vm := New()
+	for _, test := range tests {
+		value, err := vm.ToValue(test.in)
+		if err != nil {
+			t.Fatalf("ToValue(%#v) failed: %v", test.in, err)
+		}
+		number, ok := value.(*values.NumericValue)
+		if !ok || number.IsInt != test.isInt {
+			t.Errorf("ToValue(%#v) = %v, want an int: %v", test.in, value, test.isInt)
+		}
+	}
+}
+
+func TestToValueOrdersObjectKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want []string
+	}{
+		{"map[string]any", map[string]any{"c": 1, "a": 2, "b": 3}, []string{"a", "b", "c"}},
+		{"typed map", map[string]int{"z": 1, "y": 2}, []string{"y", "z"}},
+	}
+
+	vm := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := vm.ToValue(test.in)
+			if err != nil {
+				t.Fatalf("ToValue failed: %v", err)
+			}
+			object, ok := value.(*values.ObjectValue)
+			if !ok {
+				t.Fatalf("ToValue(%v) = %v, want an object", test.in, value)
+			}
+			if got := object.Keys(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("keys = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestToValueUnsupported(t *testing.T) {
+	selfSlice := []any{nil}
+	selfSlice[0] = selfSlice
+	selfMap := map[string]any{}
+	selfMap["self"] = []any{selfMap}
+
+	tests := []any{
+		struct{}{},
+		map[int]string{1: "a"},
+		make(chan int),
+		Range{Start: 1, End: 2},
+		selfSlice,
+		selfMap,
+	}
+
+	vm := New()
+	for _, in := range tests {
+		value, err := vm.ToValue(in)
+		var gloobErr *Error
+		if !goerrors.As(err, &gloobErr) || gloobErr.Kind != "CannotConvert" {
+			t.Errorf("ToValue(%T) = %v, %v, want a CannotConvert error", in, value, err)
+		}
+	}
+}
+
+func TestToValueSharedValues(t *testing.T) {
+	shared := []any{1}
+	vm := New()
+	value, err := vm.ToValue(map[string]any{"a": shared, "b": shared, "c": shared[:0]})
+	if err != nil {
+		t.Fatalf("ToValue failed: %v", err)
+	}
+	got, err := vm.FromValue(value)
+	want := map[string]any{"a": []any{int64(1)}, "b": []any{int64(1)}, "c": []any{}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %#v, %v, want %#v", got, err, want)
+	}
+}
+
+func TestFromValueErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"object containing itself", "var o = {}\no.self = o\no"},
+		{"array containing itself", "var xs = [1]\nxs.push([xs])\nxs"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := New()
+			_, err := vm.Run(test.source, "main.gloob")
+			var gloobErr *Error
+			if !goerrors.As(err, &gloobErr) || gloobErr.Kind != "CannotConvert" {
+				t.Fatalf("Run = %v, want a CannotConvert error", err)
+			}
+
+			if _, err := vm.Run("var shared = [1]\n{a: shared, b: [shared]}", "main.gloob"); err != nil {
+				t.Errorf("Run failed for a value shared twice: %v", err)
+			}
+		})
+	}
+}
+
+func TestFromValueKeepsObjectsFromScripts(t *testing.T) {
+	vm := New()
+	got, err := vm.Run(`{b: 1, a: {c: [1.5, null]}}`, "main.gloob")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := map[string]any{"b": int64(1), "a": map[string]any{"c": []any{1.5, nil}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"gloob-interpreter/internal/errors"
+	"io"
+)
+
+// Error is returned by the Interpreter when a Gloob program fails to parse or run.
+// Go functions registered with Register can also return an *Error to raise
+// a Gloob error of a custom kind, which scripts can catch by that kind name.
+type Error struct {
+	Kind    string // Name of the error kind, e.g. "DivisionByZero"
+	Message string // Human readable message
+	File    string // File the error comes from, "" when unknown
+	Line    int    // Line the error comes from, 0 when unknown
+	Column  int    // Column the error comes from, 0 when unknown
+	Syntax  bool   // Whether the program failed to parse rather than to run
+
+	err *errors.Error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return e.Message
+}
+
+// Report prints the error to w the same way the gloob command does,
+// including the offending source line when it is known.
+func (e *Error) Report(w io.Writer) {
+	if e.err != nil {
+		errors.Report(w, e.err)
+		return
+	}
+	errors.Report(w, e)
+}
+
+// newError converts an error coming from the interpreter into an *Error.
+func newError(err error) error {
+	var gloobErr *Error
+	if goerrors.As(err, &gloobErr) {
+		return gloobErr
+	}
+
+	internal := errors.From(err)
+	return &Error{
+		Kind:    internal.KindName(),
+		Message: internal.Message,
+		File:    internal.Filename(),
+		Line:    internal.Line(),
+		Column:  internal.Column(),
+		Syntax:  internal.Phase == errors.PhaseSyntax,
+		err:     internal,
+	}
+}
+
+// hostError converts an error returned by a registered Go function
+// into a Gloob runtime error, keeping the kind of an *Error.
+func hostError(err error) error {
+	var gloobErr *Error
+	if goerrors.As(err, &gloobErr) {
+		if gloobErr.err != nil {
+			return gloobErr.err
+		}
+		thrown := errors.RuntimeError(nil, "", errors.ErrThrown, gloobErr.Message)
+		thrown.CustomKind = gloobErr.Kind
+		return thrown
+	}
+	return errors.From(err)
+}
@@ -0,0 +1,79 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"testing"
+)
+
+func TestBuiltinLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		kind   string
+	}{
+		{"negative repeat", `"ab" * -1`, "NegativeRepeatCount"},
+		{"huge repeat", `"ab" * 9223372036854775807`, "RepeatTooLarge"},
+		{"randInt min above max", `randInt(5, 1)`, "RandIntRange"},
+		{"randInt overflow", `randInt(0, 9223372036854775807)`, "RandIntOverflow"},
+		{"randInt one argument", `randInt(5)`, "NativeArgCount"},
+		{"recursion", "fun f(n) { return f(n + 1) }\nf(0)", "MaxCallDepth"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New().Run(test.source, "main.gloob")
+			var gloobErr *Error
+			if !goerrors.As(err, &gloobErr) || gloobErr.Kind != test.kind {
+				t.Errorf("Run(%q) = %v, want a %s error", test.source, err, test.kind)
+			}
+		})
+	}
+}
+
+func TestBuiltinLimitsCanBeCaught(t *testing.T) {
+	tests := []struct {
+		source string
+		want   any
+	}{
+		{"fun f(n) { return f(n + 1) }\ntry { f(0) } catch e { e.kind }", "MaxCallDepth"},
+		{"fun g(n) { return match n { 0 => 0, _ => 1 + g(n - 1) } }\ng(4000)", int64(4000)},
+		{`randInt(3, 3)`, int64(3)},
+		{`"" * 9223372036854775807`, ""},
+	}
+
+	for _, test := range tests {
+		got, err := New().Run(test.source, "main.gloob")
+		if err != nil || got != test.want {
+			t.Errorf("Run(%q) = %#v, %v, want %#v", test.source, got, err, test.want)
+		}
+	}
+}
+
+func TestDeclarationErrorsHavePosition(t *testing.T) {
+	tests := []struct {
+		source string
+		line   int
+		column int
+	}{
+		{"var x = 1\nvar x = 2", 2, 5},
+		{"fun f() {}\nfun f() {}", 2, 5},
+		{"class A {}\nclass A {}", 2, 7},
+	}
+
+	for _, test := range tests {
+		_, err := New().Run(test.source, "main.gloob")
+		var gloobErr *Error
+		if !goerrors.As(err, &gloobErr) || gloobErr.Kind != "VariableAlreadyDeclared" {
+			t.Errorf("Run(%q) = %v, want a VariableAlreadyDeclared error", test.source, err)
+			continue
+		}
+		if gloobErr.Line != test.line || gloobErr.Column != test.column {
+			t.Errorf("Run(%q) reported at %d:%d, want %d:%d", test.source, gloobErr.Line, gloobErr.Column, test.line, test.column)
+		}
+	}
+
+	got, err := New().Run("try {\n  fun g() {}\n  fun g() {}\n} catch e { e.line }", "main.gloob")
+	if err != nil || got != int64(3) {
+		t.Errorf("caught error line = %#v, %v, want 3", got, err)
+	}
+}
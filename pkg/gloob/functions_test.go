@@ -0,0 +1,74 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"reflect"
+	"testing"
+)
+
+func TestParametersAndArguments(t *testing.T) {
+	vm := New()
+	_, err := vm.Run(`
+fun greet(name, greeting = "Hi") { return greeting + ", " + name }
+fun scale(x, factor = x * 2) { return x * factor }
+fun sum(...nums) { return nums.reduce((a, b) => a + b, 0) }
+fun connect(host, port = 80, secure = false) { return [host, port, secure] }
+`, "main.gloob")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	tests := []struct {
+		source string
+		want   any
+	}{
+		{`greet("Ana")`, "Hi, Ana"},
+		{`greet("Ana", "Hello")`, "Hello, Ana"},
+		{`greet(greeting: "Hey", name: "Ana")`, "Hey, Ana"},
+		{`scale(3)`, int64(18)},
+		{`sum()`, int64(0)},
+		{`sum(1, 2, 3)`, int64(6)},
+		{`sum(...[4, 5], 6)`, int64(15)},
+		{`connect("localhost", secure: true)`, []any{"localhost", int64(80), true}},
+	}
+	for _, test := range tests {
+		got, err := vm.Run(test.source, "main.gloob")
+		if err != nil {
+			t.Errorf("Run(%q) failed: %v", test.source, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Run(%q) = %#v, want %#v", test.source, got, test.want)
+		}
+	}
+
+	if got, err := vm.Call("greet", "Bo"); err != nil || got != "Hi, Bo" {
+		t.Errorf(`Call("greet", "Bo") = %#v, %v, want "Hi, Bo"`, got, err)
+	}
+}
+
+func TestArgumentErrors(t *testing.T) {
+	vm := New()
+	if _, err := vm.Run(`fun greet(name, greeting = "Hi") { return greeting + ", " + name }`, "main.gloob"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	tests := []struct {
+		source string
+		kind   string
+	}{
+		{`greet()`, "MissingArgument"},
+		{`greet("Ana", "Hi", "!")`, "FunctionArgCountMismatch"},
+		{`greet("Ana", nickname: "A")`, "UnknownNamedArgument"},
+		{`greet("Ana", name: "Bo")`, "DuplicateArgument"},
+		{`greet(...5)`, "SpreadNeedsArray"},
+		{`len(value: "abc")`, "NamedArgumentsNotSupported"},
+	}
+	for _, test := range tests {
+		_, err := vm.Run(test.source, "main.gloob")
+		var gloobErr *Error
+		if !goerrors.As(err, &gloobErr) || gloobErr.Kind != test.kind {
+			t.Errorf("Run(%q) = %v, want a %s error", test.source, err, test.kind)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package gloob lets Go programs embed the Gloob interpreter.
+//
+// An Interpreter keeps a global scope that lives across calls, so a host can
+// run a script, read and write its globals, call the functions it defines and
+// expose Go functions to it:
+//
+//	vm := gloob.New()
+//	vm.Register("greet", func(args ...any) (any, error) {
+//		return fmt.Sprintf("Hello %v", args[0]), nil
+//	})
+//	vm.Run(`function double(x) { return x * 2 }`, "main.gloob")
+//	result, err := vm.Call("double", 21) // int64(42)
+//
+// Values cross the boundary as plain Go values, see ToValue and FromValue
+// for the conversion rules.
+package gloob
+
+import (
+	"gloob-interpreter/internal/builtins"
+	"gloob-interpreter/internal/interpreter"
+	"gloob-interpreter/internal/parser"
+	"gloob-interpreter/internal/scope"
+)
+
+// Func is the signature of Go functions registered into an Interpreter.
+// Arguments are converted with FromValue and the result with ToValue.
+// A returned error is raised as a Gloob runtime error, which scripts can catch.
+type Func func(args ...any) (any, error)
+
+// Interpreter runs Gloob code against a global scope that persists between calls.
+// An Interpreter is not safe for concurrent use.
+type Interpreter struct {
+	scope *scope.Scope
+}
+
+// New creates an Interpreter with all the built-in functions and constants declared.
+func New() *Interpreter {
+	return &Interpreter{scope: builtins.NewGlobalScope()}
+}
+
+// Run parses and evaluates source.
+// The filename is used in error messages and as the base for relative imports;
+// it doesn't need to exist on disk. The value of the last statement is returned.
+// Imported modules are evaluated once per Interpreter, the first time they are imported.
+func (i *Interpreter) Run(source string, filename string) (any, error) {
+	p := parser.NewParser(nil)
+	program, err := p.ProduceASTWithFilename(source, filename)
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	i.scope.SetSourceCode(source)
+	result, err := interpreter.Evaluate(program, i.scope)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return i.FromValue(result)
+}
+
+// Call calls the global function fnName with the given arguments.
+func (i *Interpreter) Call(fnName string, args ...any) (any, error) {
+	function, err := i.scope.Get(fnName)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return i.call(function, args)
+}
+
+// Get returns the value of a global variable.
+func (i *Interpreter) Get(name string) (any, error) {
+	value, err := i.scope.Get(name)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return i.FromValue(value)
+}
+
+// Set assigns a global variable, declaring it when it doesn't exist yet.
+// Constants cannot be set.
+func (i *Interpreter) Set(name string, value any) error {
+	runtimeValue, err := i.ToValue(value)
+	if err != nil {
+		return err
+	}
+
+	if i.scope.Resolve(name) != nil {
+		_, err = i.scope.Assign(name, runtimeValue)
+	} else {
+		_, err = i.scope.Declare(name, runtimeValue, false)
+	}
+	if err != nil {
+		return newError(err)
+	}
+	return nil
+}
+
+// Register exposes fn to Gloob code as a global constant function called name.
+// Like the built-in functions, it can be called from the modules the scripts import too.
+func (i *Interpreter) Register(name string, fn Func) error {
+	if err := builtins.DeclareNativeFunction(i.scope.Root(), name, i.native(fn)); err != nil {
+		return newError(err)
+	}
+	return nil
+}
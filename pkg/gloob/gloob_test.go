@@ -0,0 +1,241 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   any
+	}{
+		{"arithmetic", `1 + 2 * 3`, int64(7)},
+		{"string", `"Glo" + "ob"`, "Gloob"},
+		{"last statement", "var x = 1\nx + 1", int64(2)},
+		{"array", `[1, 2, 3].map(x => x * 2)`, []any{int64(2), int64(4), int64(6)}},
+		{"null", `null`, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := New().Run(test.source, "main.gloob")
+			if err != nil {
+				t.Fatalf("Run(%q) failed: %v", test.source, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Run(%q) = %#v, want %#v", test.source, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunKeepsGlobals(t *testing.T) {
+	vm := New()
+	if _, err := vm.Run(`var count = 1`, "first.gloob"); err != nil {
+		t.Fatalf("first Run failed: %v", err)
+	}
+	got, err := vm.Run(`count = count + 1`, "second.gloob")
+	if err != nil {
+		t.Fatalf("second Run failed: %v", err)
+	}
+	if got != int64(2) {
+		t.Errorf("count = %#v, want 2", got)
+	}
+}
+
+func TestCall(t *testing.T) {
+	vm := New()
+	_, err := vm.Run(`
+fun double(x) { return x * 2 }
+fun greet(name) { return "Hello " + name }
+fun apply(f, x) { return f(x) }
+`, "main.gloob")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	tests := []struct {
+		fn   string
+		args []any
+		want any
+	}{
+		{"double", []any{21}, int64(42)},
+		{"double", []any{1.5}, 3.0},
+		{"greet", []any{"Gloob"}, "Hello Gloob"},
+		{"apply", []any{Func(func(args ...any) (any, error) { return args[0].(int64) + 1, nil }), 1}, int64(2)},
+	}
+
+	for _, test := range tests {
+		got, err := vm.Call(test.fn, test.args...)
+		if err != nil {
+			t.Errorf("Call(%q, %v) failed: %v", test.fn, test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Call(%q, %v) = %#v, want %#v", test.fn, test.args, got, test.want)
+		}
+	}
+}
+
+func TestCallReturnedFunction(t *testing.T) {
+	vm := New()
+	value, err := vm.Run(`fun adder(n) { return x => x + n }
+adder(10)`, "main.gloob")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	function, ok := value.(*Function)
+	if !ok {
+		t.Fatalf("Run returned %#v, want a *Function", value)
+	}
+	got, err := function.Call(5)
+	if err != nil || got != int64(15) {
+		t.Errorf("Call(5) = %#v, %v, want 15", got, err)
+	}
+}
+
+func TestGetAndSet(t *testing.T) {
+	vm := New()
+	if _, err := vm.Run(`var name = "Gloob"
+const limit = 3`, "main.gloob"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"name", "Gloob 2"},
+		{"fresh", []any{int64(1), "two"}},
+		{"object", map[string]any{"ok": true}},
+	}
+	for _, test := range tests {
+		if err := vm.Set(test.name, test.value); err != nil {
+			t.Errorf("Set(%q) failed: %v", test.name, err)
+			continue
+		}
+		got, err := vm.Get(test.name)
+		if err != nil {
+			t.Errorf("Get(%q) failed: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.value) {
+			t.Errorf("Get(%q) = %#v, want %#v", test.name, got, test.value)
+		}
+	}
+
+	got, err := vm.Run(`name + " " + len(fresh)`, "main.gloob")
+	if err != nil || got != "Gloob 2 2" {
+		t.Errorf("script sees %#v, %v, want \"Gloob 2 2\"", got, err)
+	}
+
+	for _, name := range []string{"limit", "println"} {
+		if err := vm.Set(name, 1); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error for a constant", name)
+		}
+	}
+	if _, err := vm.Get("missing"); err == nil {
+		t.Error("Get(\"missing\") succeeded, want an error")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	vm := New()
+	err := vm.Register("greet", func(args ...any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("greet expects a name")
+		}
+		return fmt.Sprintf("Hello %v", args[0]), nil
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	got, err := vm.Run(`greet("Gloob")`, "main.gloob")
+	if err != nil || got != "Hello Gloob" {
+		t.Errorf(`greet("Gloob") = %#v, %v, want "Hello Gloob"`, got, err)
+	}
+
+	got, err = vm.Run(`try { greet() } catch e { e.message }`, "main.gloob")
+	if err != nil || got != "greet expects a name" {
+		t.Errorf("caught %#v, %v, want the Go error message", got, err)
+	}
+
+	if err := vm.Register("greet", func(args ...any) (any, error) { return nil, nil }); err == nil {
+		t.Error("registering greet twice succeeded, want an error")
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		kind   string
+		syntax bool
+		line   int
+	}{
+		{"syntax", "var x = 1\nvar = 2", "ExpectedIdentifier", true, 2},
+		{"division by zero", "var x = 1\nx / 0", "DivisionByZero", false, 2},
+		{"missing variable", `missing + 1`, "VariableNotFound", false, 1},
+		{"argument count", "fun f(a) { a }\nf(1, 2)", "FunctionArgCountMismatch", false, 2},
+		{"custom throw", `throw {kind: "Custom", message: "boom"}`, "Custom", false, 1},
+		{"host error", `fail()`, "HostKind", false, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := New()
+			vm.Register("fail", func(args ...any) (any, error) {
+				return nil, &Error{Kind: "HostKind", Message: "failed in Go"}
+			})
+			_, err := vm.Run(test.source, "main.gloob")
+
+			var gloobErr *Error
+			if !goerrors.As(err, &gloobErr) {
+				t.Fatalf("Run(%q) = %v, want an *Error", test.source, err)
+			}
+			if gloobErr.Kind != test.kind || gloobErr.Syntax != test.syntax {
+				t.Errorf("got kind %q (syntax: %v), want %q (syntax: %v)", gloobErr.Kind, gloobErr.Syntax, test.kind, test.syntax)
+			}
+			if gloobErr.Line != test.line || gloobErr.File != "main.gloob" {
+				t.Errorf("got position %s:%d, want main.gloob:%d", gloobErr.File, gloobErr.Line, test.line)
+			}
+		})
+	}
+}
+
+func TestErrorsShowTheirOwnSource(t *testing.T) {
+	vm := New()
+	if _, err := vm.Run("fun f() {\n  return 1 / 0\n}\nclass P {\n  x = 1 / 0\n}", "a.gloob"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	tests := []struct {
+		source string
+		line   int
+		shown  string
+	}{
+		{"var unrelated = 1\nvar other = f()", 2, "return 1 / 0"},
+		{"var more = 1\nvar point = P()", 5, "x = 1 / 0"},
+	}
+	for _, test := range tests {
+		_, err := vm.Run(test.source, "b.gloob")
+		var gloobErr *Error
+		if !goerrors.As(err, &gloobErr) {
+			t.Fatalf("Run(%q) = %v, want an *Error", test.source, err)
+		}
+		if gloobErr.File != "a.gloob" || gloobErr.Line != test.line {
+			t.Errorf("got position %s:%d, want a.gloob:%d", gloobErr.File, gloobErr.Line, test.line)
+		}
+		var report strings.Builder
+		gloobErr.Report(&report)
+		if !strings.Contains(report.String(), test.shown) {
+			t.Errorf("report doesn't show %q:\n%s", test.shown, report.String())
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"fmt"
+	"gloob-interpreter/internal/imports"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeModules writes files, by name, into a temporary directory and returns it.
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImports(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"counter.gloob": "var n = 0\nexport fun next() {\n  n = n + 1\n  return n\n}\n",
+		"left.gloob":    "import \"counter\" as c\nexport const value = c.next()\n",
+		"right.gloob":   "import \"counter\" as c\nexport const value = c.next()\n",
+		"private.gloob": "fun helper() { return 1 }\nexport fun visible() { return helper() + 1 }\n",
+		"all.gloob":     "const one = 1\nfun two() { return 2 }\n",
+		"a.gloob":       "import \"b\"\n",
+		"b.gloob":       "import \"a\"\n",
+	})
+	main := filepath.Join(dir, "main.gloob")
+
+	tests := []struct {
+		name   string
+		source string
+		want   any
+		kind   string
+	}{
+		{"diamond", "import \"left\" as l\nimport \"right\" as r\n[l.value, r.value]", []any{int64(1), int64(2)}, ""},
+		{"module object", "import \"private\" as p\np.visible()", int64(2), ""},
+		{"all exports", "import \"private\"\nvisible()", int64(2), ""},
+		{"no export exports everything", "from \"all\" import one, two\none + two()", int64(3), ""},
+		{"std", "import \"std/json\" as json\njson.stringify({a: [1, 2]})", `{"a":[1,2]}`, ""},
+		{"missing std", `import "std/nope"`, nil, "ImportFailed"},
+		{"missing", `import "missing"`, nil, "ImportFailed"},
+		{"not exported", `from "private" import helper`, nil, "NotExported"},
+		{"circular", `import "a"`, nil, "CircularImport"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := New().Run(test.source, main)
+			if test.kind != "" {
+				var gloobErr *Error
+				if !goerrors.As(err, &gloobErr) || gloobErr.Kind != test.kind {
+					t.Fatalf("Run = %#v, %v, want a %s error", got, err, test.kind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Run = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestImportSearchPath(t *testing.T) {
+	root := writeModules(t, map[string]string{
+		imports.ProjectMarker: "",
+		"helpers.gloob":       "export const where = \"root\"\n",
+	})
+	lib := writeModules(t, map[string]string{"extra.gloob": "export const where = \"lib\"\n"})
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(root, "sub", "main.gloob")
+	imports.AddSearchPath(lib)
+
+	got, err := New().Run("import \"helpers\" as h\nimport \"extra\" as e\n[h.where, e.where]", main)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []any{"root", "lib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Run = %#v, want %#v", got, want)
+	}
+
+	// ./ paths are only looked up next to the importing file
+	_, err = New().Run(`import "./helpers"`, main)
+	var gloobErr *Error
+	if !goerrors.As(err, &gloobErr) || gloobErr.Kind != "ImportFailed" {
+		t.Errorf("Run = %v, want an ImportFailed error", err)
+	}
+}
+
+func TestCircularImportChain(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.gloob": "import \"b\"\n",
+		"b.gloob": "import \"c\"\n",
+		"c.gloob": "import \"a\"\n",
+	})
+
+	_, err := New().Run(`import "a"`, filepath.Join(dir, "main.gloob"))
+	var gloobErr *Error
+	if !goerrors.As(err, &gloobErr) || gloobErr.Kind != "CircularImport" {
+		t.Fatalf("Run = %v, want a CircularImport error", err)
+	}
+	module := func(name string) string { return filepath.Join(dir, name+".gloob") }
+	if want := strings.Join([]string{module("a"), module("b"), module("c"), module("a")}, " -> "); !strings.Contains(gloobErr.Message, want) {
+		t.Errorf("message %q doesn't show the chain %q", gloobErr.Message, want)
+	}
+	// The error points at the import closing the circle
+	if gloobErr.File != module("c") || gloobErr.Line != 1 {
+		t.Errorf("got position %s:%d, want %s:1", gloobErr.File, gloobErr.Line, module("c"))
+	}
+}
+
+func TestImportsSeeRegisteredNatives(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"greeting.gloob": "export fun hello() { return greet(\"module\") + \" \" + round(pi) }\n",
+	})
+
+	vm := New()
+	vm.Register("greet", func(args ...any) (any, error) { return fmt.Sprintf("Hello %v", args[0]), nil })
+	got, err := vm.Run("from \"greeting\" import hello\nhello()", filepath.Join(dir, "main.gloob"))
+	if err != nil || got != "Hello module 3" {
+		t.Errorf("Run = %#v, %v, want \"Hello module 3\"", got, err)
+	}
+}
+
+func TestImportsFromReusedInterpreter(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"d.gloob":     "export const value = 7\n",
+		"first.gloob": "import \"back\"\n",
+		"back.gloob":  "import \"first\"\n",
+	})
+
+	vm := New()
+	if _, err := vm.Run("import \"std/json\" as json\nexport const value = 7\n", filepath.Join(dir, "d.gloob")); err != nil {
+		t.Fatalf("first Run failed: %v", err)
+	}
+
+	// The file run first is done, importing it isn't circular
+	got, err := vm.Run("import \"d\" as d\nd.value", filepath.Join(dir, "main.gloob"))
+	if err != nil || got != int64(7) {
+		t.Errorf("second Run = %#v, %v, want 7", got, err)
+	}
+
+	// Cycles through a later main file are still detected
+	_, err = vm.Run(`import "back"`, filepath.Join(dir, "first.gloob"))
+	var gloobErr *Error
+	if !goerrors.As(err, &gloobErr) || gloobErr.Kind != "CircularImport" {
+		t.Fatalf("third Run = %v, want a CircularImport error", err)
+	}
+	if want := "first.gloob -> "; !strings.Contains(gloobErr.Message, want) {
+		t.Errorf("message %q doesn't show the import chain", gloobErr.Message)
+	}
+}
@@ -0,0 +1,67 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"reflect"
+	"testing"
+)
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		source string
+		want   any
+	}{
+		{`7 / 2`, int64(3)},
+		{`7.0 / 2`, 3.5},
+		{`-7 % 3`, int64(-1)},
+		{`2.0`, 2.0},
+		{`9007199254740993 + 1`, int64(9007199254740994)},
+		{`1 == 1.0`, true},
+		{`[1, 2, 3][2]`, int64(2)},
+		{`"abc"[-1]`, "c"},
+		{`"ab" * 3`, "ababab"},
+		{`string(7)`, "7"},
+		{`string(2.0)`, "2.0"},
+		{`string(1e301)`, "1e+301"},
+		{"string(2.5) == `${2.5}`", true},
+		{"var xs = []\nloop i from 9007199254740993 to 9007199254740995 { xs.push(i) }\nxs",
+			[]any{int64(9007199254740993), int64(9007199254740994), int64(9007199254740995)}},
+		{"var xs = []\nloop i from 9223372036854775806 to 9223372036854775807 { xs.push(i) }\nxs",
+			[]any{int64(9223372036854775806), int64(9223372036854775807)}},
+		{"var xs = []\nloop i from 3 to 1 { xs.push(i) }\nxs", []any{int64(3), int64(2), int64(1)}},
+		{"var xs = []\nloop i from 0 to 1: 0.5 { xs.push(i) }\nxs", []any{0.0, 0.5, 1.0}},
+	}
+
+	for _, test := range tests {
+		got, err := New().Run(test.source, "main.gloob")
+		if err != nil {
+			t.Errorf("Run(%q) failed: %v", test.source, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Run(%q) = %#v, want %#v", test.source, got, test.want)
+		}
+	}
+}
+
+func TestNumberErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   string
+	}{
+		{`9223372036854775807 + 1`, "IntegerOverflow"},
+		{`[1, 2][1.5]`, "IndexMustBeInt"},
+		{`"abc"[2.0]`, "IndexMustBeInt"},
+		{"var a = [1, 2]\na[1.5] = 3", "IndexMustBeInt"},
+		{`"ab" * 2.7`, "RepeatCountNeedsInt"},
+		{`[1, 2].remove(1.5)`, "NativeArgType"},
+	}
+
+	for _, test := range tests {
+		_, err := New().Run(test.source, "main.gloob")
+		var gloobErr *Error
+		if !goerrors.As(err, &gloobErr) || gloobErr.Kind != test.kind {
+			t.Errorf("Run(%q) = %v, want a %s error", test.source, err, test.kind)
+		}
+	}
+}
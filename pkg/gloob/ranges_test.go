@@ -0,0 +1,59 @@
+package gloob
+
+import (
+	goerrors "errors"
+	"reflect"
+	"testing"
+)
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		source string
+		want   any
+	}{
+		{"var xs = []\nloop n from 1..10:3 { xs.push(n) }\nxs", []any{int64(1), int64(4), int64(7), int64(10)}},
+		{"var xs = []\nloop n from 3..1 { xs.push(n) }\nxs", []any{int64(3), int64(2), int64(1)}},
+		{"var xs = []\nloop n from 1.. {\n  if n > 3 { break }\n  xs.push(n)\n}\nxs", []any{int64(1), int64(2), int64(3)}},
+		{"var xs = []\nloop n from 9223372036854775806..9223372036854775807 { xs.push(n) }\nxs",
+			[]any{int64(9223372036854775806), int64(9223372036854775807)}},
+		{"var xs = []\nloop n from 9223372036854775806.. { xs.push(n) }\nxs",
+			[]any{int64(9223372036854775806), int64(9223372036854775807)}},
+		{"var xs = []\nloop n from (-9223372036854775807 + 1)..(-9223372036854775807 - 1):-2 { xs.push(n) }\nxs",
+			[]any{int64(-9223372036854775806), int64(-9223372036854775808)}},
+		{`[1, 2, 3, 4, 5][2..-2]`, []any{int64(2), int64(3), int64(4)}},
+		{`[1, 2, 3, 4, 5][4..]`, []any{int64(4), int64(5)}},
+		{`[1, 2, 3][-1..1]`, []any{int64(3), int64(2), int64(1)}},
+		{`"hello"[2..4]`, "ell"},
+		{`[1, 2, 3][-1]`, int64(3)},
+	}
+
+	for _, test := range tests {
+		got, err := New().Run(test.source, "main.gloob")
+		if err != nil {
+			t.Errorf("Run(%q) failed: %v", test.source, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Run(%q) = %#v, want %#v", test.source, got, test.want)
+		}
+	}
+}
+
+func TestRangeErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   string
+	}{
+		{`1..2.5`, "RangeNeedsInt"},
+		{`1..5:0`, "RangeStepZero"},
+		{`[1, 2, 3][2..5]`, "ArrayIndexOutOfBounds"},
+	}
+
+	for _, test := range tests {
+		_, err := New().Run(test.source, "main.gloob")
+		var gloobErr *Error
+		if !goerrors.As(err, &gloobErr) || gloobErr.Kind != test.kind {
+			t.Errorf("Run(%q) = %v, want a %s error", test.source, err, test.kind)
+		}
+	}
+}